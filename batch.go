@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"vertex-nano-banana-unlimited/internal/app"
+)
+
+// runBatch 实现 batch 子命令：执行清单或恢复中断的批次，最终批次（含报告路径）以 JSON 写到 stdout。
+// 返回进程退出码：0 批次完成且无失败条目，1 有失败或中断，2 参数错误。
+func runBatch(args []string) int {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	manifest := fs.String("manifest", "", "JSONL 或 CSV 清单路径（列：prompt,image,resolution,temperature,count）")
+	resume := fs.String("resume", "", "继续执行已有批次的 ID")
+	headful := fs.Bool("headful", false, "显示浏览器窗口")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	*manifest, *resume = strings.TrimSpace(*manifest), strings.TrimSpace(*resume)
+	if (*manifest == "") == (*resume == "") {
+		fmt.Fprintln(os.Stderr, "❌ 需要且只能指定 --manifest 或 --resume 之一")
+		return 2
+	}
+
+	stdout, restore := logsToStderr()
+	defer restore()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer app.Shutdown()
+
+	var (
+		batch app.Batch
+		err   error
+	)
+	if *resume != "" {
+		batch, err = app.ResumeBatch(ctx, *resume)
+	} else {
+		batch, err = app.RunBatch(ctx, *manifest, !*headful)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ batch 失败: %v\n", err)
+		return 2
+	}
+	printJSON(stdout, batch)
+	if batch.Status != app.BatchCompleted {
+		fmt.Fprintf(os.Stderr, "ℹ️ 批次未完成，可用 batch --resume %s 继续\n", batch.ID)
+		return 1
+	}
+	if batch.Summary.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -19,7 +19,7 @@ export interface BackendRunResponse {
 
 export interface ScenarioResult {
   id: number;
-  outcome: 'downloaded' | 'exhausted' | 'none';
+  outcome: 'downloaded' | 'exhausted' | 'deadline' | 'timeout' | 'cancelled' | 'submit_failed' | 'token_invalid' | 'blocked' | 'text' | 'none';
   path: string;
   url: string;
   proxyTag?: string;
@@ -53,6 +53,7 @@ export interface HealthResponse {
 
 export interface CancelResponse {
   status: string;
+  jobId?: string;
 }
 
 // 后端API基础URL - 开发环境配置
@@ -102,13 +103,14 @@ class BackendService {
     return response.json();
   }
 
-  // 取消当前运行
-  async cancelRun(): Promise<CancelResponse> {
+  // 取消指定任务（/run 返回的 jobId）
+  async cancelRun(jobId: string): Promise<CancelResponse> {
     const response = await fetch(`${this.baseUrl}/cancel`, {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
       },
+      body: JSON.stringify({ jobId }),
     });
     if (!response.ok) {
       throw new Error(`Cancel failed: ${response.status}`);
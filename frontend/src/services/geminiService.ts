@@ -127,7 +127,13 @@ export class GeminiServiceAdapter {
       }
     }
 
-    const imageUrls = successResults.map(result => {
+    const imageUrls = successResults.flatMap(result => {
+      if (result.urls && result.urls.length > 1) {
+        // 一个场景可能返回多张候选图片
+        const fullUrls = result.urls.map(u => goBackendService.getImageUrl(u));
+        console.log(`🔗 场景${result.id} ${fullUrls.length} 张图片:`, fullUrls);
+        return fullUrls;
+      }
       if (result.url) {
         // Go后端返回的URL已经是完整路径（以/开头），直接构建完整URL
         const fullUrl = result.url.startsWith('/') ?
@@ -251,10 +257,10 @@ export class GeminiServiceAdapter {
     }
   }
 
-  // 取消当前运行（Go后端特有功能）
-  async cancelRun() {
+  // 取消本页面发起的运行，或只取消 jobId 指定的任务（Go后端特有功能）
+  async cancelRun(jobId?: string) {
     try {
-      return await goBackendService.cancelRun();
+      return await goBackendService.cancelRun(jobId);
     } catch (error) {
       console.error('取消运行失败:', error);
       throw error;
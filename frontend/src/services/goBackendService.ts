@@ -5,6 +5,7 @@
 export interface GoBackendRunRequest {
   prompt: string;
   image?: File | string;  // File对象（multipart请求）或本地图片路径（JSON请求）
+  extraImages?: File[];   // 额外参考图片，按顺序排在 image 之后上传（仅 multipart）
   scenarioCount?: number;
   resolution?: string;
 }
@@ -18,11 +19,31 @@ export interface GoBackendRunResponse {
   error?: string;
 }
 
+export interface GoBackendJob {
+  id: string;
+  status: 'queued' | 'running' | 'succeeded' | 'failed' | 'cancelled';
+  prompt: string;
+  imageOrig?: string;
+  imageUsed?: string;
+  scenarioCount: number;
+  outputRes: string;
+  temperature: number;
+  results?: GoBackendScenarioResult[];
+  error?: string;
+  createdAt: string;
+  startedAt?: string;
+  finishedAt?: string;
+}
+
 export interface GoBackendScenarioResult {
   id: number;
-  outcome: 'downloaded' | 'exhausted' | 'none';
+  outcome: 'downloaded' | 'exhausted' | 'deadline' | 'timeout' | 'cancelled' | 'submit_failed' | 'token_invalid' | 'blocked' | 'text' | 'none';
   path: string;
   url: string;
+  paths?: string[];
+  urls?: string[];
+  text?: string;
+  blockReason?: string;
   proxyTag?: string;
   outputRes?: string;
   error?: string;
@@ -54,6 +75,7 @@ export interface GoBackendHealthResponse {
 
 export interface GoBackendCancelResponse {
   status: string;
+  jobId?: string;
 }
 
 export interface ProxySubscriptionsResponse {
@@ -65,6 +87,8 @@ export interface ProxySubscriptionsResponse {
 // Go后端服务类
 export class GoBackendService {
   private baseUrl: string;
+  // 本页面发起且尚未结束的任务，取消时只取消这些任务
+  private activeJobIds = new Set<string>();
 
   constructor(baseUrl?: string) {
     this.baseUrl = baseUrl || 'http://localhost:8080';
@@ -79,6 +103,21 @@ export class GoBackendService {
       throw new Error(`Go后端生成失败: 无法解析响应 ${response.status} ${response.statusText} - ${raw}`);
     }
 
+    // /run 现在异步返回任务 ID，轮询 /jobs/{id} 直到任务结束
+    if (response.ok && data.jobId) {
+      const jobId: string = data.jobId;
+      this.activeJobIds.add(jobId);
+      const job = await this.waitForJob(jobId).finally(() => this.activeJobIds.delete(jobId));
+      data = {
+        status: job.status,
+        imageUsed: job.imageUsed,
+        imageOrig: job.imageOrig,
+        scenarioCount: job.scenarioCount,
+        results: job.results,
+        error: job.error,
+      };
+    }
+
     const results = Array.isArray(data.results)
       ? (data.results as GoBackendScenarioResult[]).filter(r => r.outcome === 'downloaded')
       : [];
@@ -98,6 +137,25 @@ export class GoBackendService {
     throw new Error(`Go后端生成失败: ${msg}`);
   }
 
+  // 查询任务状态
+  async getJob(id: string): Promise<GoBackendJob> {
+    const response = await fetch(`${this.baseUrl}/jobs/${encodeURIComponent(id)}`);
+    if (!response.ok) {
+      throw new Error(`Go后端任务查询失败: ${response.status} ${response.statusText}`);
+    }
+    return response.json();
+  }
+
+  private async waitForJob(id: string, intervalMs = 2000): Promise<GoBackendJob> {
+    for (;;) {
+      const job = await this.getJob(id);
+      if (job.status !== 'queued' && job.status !== 'running') {
+        return job;
+      }
+      await new Promise(resolve => setTimeout(resolve, intervalMs));
+    }
+  }
+
   // 健康检查
   async healthCheck(): Promise<GoBackendHealthResponse> {
     const response = await fetch(`${this.baseUrl}/healthz`);
@@ -107,18 +165,30 @@ export class GoBackendService {
     return response.json();
   }
 
-  // 取消当前运行
-  async cancelRun(): Promise<GoBackendCancelResponse> {
-    const response = await fetch(`${this.baseUrl}/cancel`, {
-      method: 'POST',
-      headers: {
-        'Content-Type': 'application/json',
-      },
-    });
-    if (!response.ok) {
-      throw new Error(`Go后端取消运行失败: ${response.status} ${response.statusText}`);
+  // 本页面发起且尚未结束的任务 ID
+  getActiveJobIds(): string[] {
+    return [...this.activeJobIds];
+  }
+
+  // 取消运行：指定 jobId 时只取消该任务，否则取消本页面发起的全部任务
+  async cancelRun(jobId?: string): Promise<GoBackendCancelResponse[]> {
+    const ids = jobId ? [jobId] : this.getActiveJobIds();
+    if (ids.length === 0) {
+      return [{ status: 'idle' }];
     }
-    return response.json();
+    return Promise.all(ids.map(async id => {
+      const response = await fetch(`${this.baseUrl}/cancel`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ jobId: id }),
+      });
+      if (!response.ok) {
+        throw new Error(`Go后端取消运行失败: ${response.status} ${response.statusText}`);
+      }
+      return response.json() as Promise<GoBackendCancelResponse>;
+    }));
   }
 
   // 生成图片（支持File对象和本地路径）
@@ -139,8 +209,11 @@ export class GoBackendService {
         formData.append('resolution', request.resolution);
       }
 
-      // 添加图片文件
+      // 添加图片文件（image 字段可重复，按顺序上传）
       formData.append('image', request.image);
+      for (const extra of request.extraImages ?? []) {
+        formData.append('image', extra);
+      }
 
       const response = await fetch(`${this.baseUrl}/run`, {
         method: 'POST',
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"vertex-nano-banana-unlimited/internal/app"
+)
+
+// runGenerate 实现 generate 子命令：参数直接映射到 RunOptions，结果以 JSON 写到 stdout。
+// 返回进程退出码：0 至少有一张图片生成成功，1 运行失败，2 参数错误。
+func runGenerate(args []string) int {
+	opts := app.DefaultRunOptions()
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	prompt := fs.String("prompt", "", "提示词（必填）")
+	var images stringList
+	fs.Var(&images, "image", "参考图片路径，可重复指定多张（按顺序上传），留空为纯文本生成")
+	fs.IntVar(&opts.ScenarioCount, "count", opts.ScenarioCount, "并发场景数")
+	fs.IntVar(&opts.TargetImages, "target", 0, "目标成功图片数，>0 时换节点重试直到达成")
+	fs.DurationVar(&opts.TargetDeadline, "deadline", 0, "目标模式的截止时间，例如 30m")
+	fs.StringVar(&opts.Model, "model", opts.Model, "模型 ID，需在配置的 models.supported 中")
+	fs.StringVar(&opts.OutputRes, "res", opts.OutputRes, "输出分辨率，例如 1K/2K/4K")
+	fs.Float64Var(&opts.Temperature, "temp", opts.Temperature, "温度 (0-2)")
+	fs.StringVar(&opts.AspectRatio, "aspect", "", "输出宽高比，例如 1:1、16:9、9:16，留空使用页面默认值")
+	fs.IntVar(&opts.CandidateCount, "candidates", 0, "每次提交生成的候选图片数，0 使用页面默认值")
+	fs.StringVar(&opts.DownloadDir, "out", opts.DownloadDir, "输出目录")
+	headful := fs.Bool("headful", false, "显示浏览器窗口")
+	fs.BoolVar(&opts.Trace, "trace", false, "为每个场景录制 Playwright trace.zip")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	opts.PromptText = strings.TrimSpace(*prompt)
+	if opts.PromptText == "" {
+		fmt.Fprintln(os.Stderr, "❌ --prompt 不能为空")
+		return 2
+	}
+	if opts.Temperature < 0 || opts.Temperature > 2 {
+		fmt.Fprintln(os.Stderr, "❌ --temp 需在 0 到 2 之间")
+		return 2
+	}
+	if _, err := app.ResolveModel(opts.Model); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	if err := app.ValidateModelSettings(opts.AspectRatio, opts.CandidateCount); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	opts.Headless = !*headful
+
+	stdout, restore := logsToStderr()
+	defer restore()
+
+	processed, err := app.PrepareImages(images)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 处理图片失败: %v\n", err)
+		return 2
+	}
+	opts.ImagePaths = processed
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer app.Shutdown()
+
+	started := time.Now()
+	job := app.RunJob(ctx, opts, images)
+	fmt.Fprintf(os.Stderr, "🏁 generate %s in %s\n", job.Status, time.Since(started).Round(time.Second))
+
+	printJSON(stdout, job)
+	if job.Status != app.JobSucceeded {
+		return 1
+	}
+	// 场景全部被拦截、只回复文本或配额耗尽时任务本身不算失败，但没有产出图片
+	if job.Downloaded() == 0 {
+		fmt.Fprintln(os.Stderr, "❌ 没有生成任何图片")
+		return 1
+	}
+	return 0
+}
+
+// logsToStderr 把运行过程的日志改写到 stderr，stdout 只保留最终 JSON，便于脚本解析。
+// 返回原始 stdout 与恢复函数。
+func logsToStderr() (*os.File, func()) {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	return stdout, func() { os.Stdout = stdout }
+}
+
+func printJSON(w *os.File, v any) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// stringList 是可重复指定的字符串参数，忽略空值。
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	if v = strings.TrimSpace(v); v != "" {
+		*l = append(*l, v)
+	}
+	return nil
+}
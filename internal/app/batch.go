@@ -0,0 +1,632 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type BatchStatus string
+
+const (
+	BatchRunning   BatchStatus = "running"
+	BatchCompleted BatchStatus = "completed"
+	BatchCancelled BatchStatus = "cancelled"
+)
+
+type BatchItemStatus string
+
+const (
+	BatchItemPending BatchItemStatus = "pending"
+	BatchItemRunning BatchItemStatus = "running"
+	BatchItemDone    BatchItemStatus = "done"
+	BatchItemFailed  BatchItemStatus = "failed"
+)
+
+const (
+	batchStateFile  = "state.json"
+	batchReportFile = "report.json"
+	// maxBatchImageBytes 清单中通过 URL 引用的图片大小上限。
+	maxBatchImageBytes = 32 << 20
+)
+
+// BatchItem 是清单中的一行及其执行结果。
+type BatchItem struct {
+	Index       int              `json:"index"`
+	Prompt      string           `json:"prompt"`
+	Image       string           `json:"image,omitempty"`
+	Resolution  string           `json:"resolution,omitempty"`
+	Temperature float64          `json:"temperature,omitempty"`
+	Count       int              `json:"count,omitempty"`
+	Status      BatchItemStatus  `json:"status"`
+	JobID       string           `json:"jobId,omitempty"`
+	Results     []ScenarioResult `json:"results,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// Batch 是一次清单批量生成；状态持久化在 Dir/state.json，进程重启后可继续执行。
+type Batch struct {
+	ID         string       `json:"id"`
+	Status     BatchStatus  `json:"status"`
+	Dir        string       `json:"dir"`
+	Headless   bool         `json:"headless"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	UpdatedAt  time.Time    `json:"updatedAt"`
+	FinishedAt *time.Time   `json:"finishedAt,omitempty"`
+	Summary    BatchSummary `json:"summary"`
+	Items      []BatchItem  `json:"items"`
+}
+
+type BatchSummary struct {
+	Total   int `json:"total"`
+	Done    int `json:"done"`
+	Failed  int `json:"failed"`
+	Pending int `json:"pending"`
+	Images  int `json:"images"`
+}
+
+func (b *Batch) summarize() {
+	s := BatchSummary{Total: len(b.Items)}
+	for _, it := range b.Items {
+		switch it.Status {
+		case BatchItemDone:
+			s.Done++
+		case BatchItemFailed:
+			s.Failed++
+		default:
+			s.Pending++
+		}
+		s.Images += downloadedImages(it.Results)
+	}
+	b.Summary = s
+}
+
+func (b Batch) clone() Batch {
+	b.Items = append([]BatchItem(nil), b.Items...)
+	return b
+}
+
+type batchRun struct {
+	batch     Batch
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+type batchManager struct {
+	mu      sync.Mutex
+	batches map[string]*batchRun
+}
+
+var batches = &batchManager{batches: map[string]*batchRun{}}
+
+func batchesDir() string {
+	return filepath.Join(settings.Runner.DownloadDir, "batches")
+}
+
+// create 解析后的清单落盘为新批次，返回其快照（尚未开始执行）。
+func (m *batchManager) create(items []BatchItem, manifest []byte, ext string, headless bool) (Batch, error) {
+	if len(items) == 0 {
+		return Batch{}, errors.New("清单为空")
+	}
+	id := newJobID()
+	dir := filepath.Join(batchesDir(), id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Batch{}, fmt.Errorf("make batch dir: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest"+ext), manifest, 0o644); err != nil {
+		return Batch{}, fmt.Errorf("save manifest: %w", err)
+	}
+	now := time.Now()
+	b := Batch{
+		ID:        id,
+		Status:    BatchRunning,
+		Dir:       dir,
+		Headless:  headless,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Items:     items,
+	}
+	b.summarize()
+	if err := writeJSONFile(filepath.Join(dir, batchStateFile), b); err != nil {
+		return Batch{}, fmt.Errorf("save batch state: %w", err)
+	}
+	m.mu.Lock()
+	m.batches[id] = &batchRun{batch: b}
+	m.mu.Unlock()
+	return b.clone(), nil
+}
+
+// run 顺序处理批次中未完成的条目，直到全部完成、被取消或 ctx 结束（后者保留进度以便恢复）。
+func (m *batchManager) run(ctx context.Context, id string) Batch {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m.mu.Lock()
+	run, ok := m.batches[id]
+	if !ok {
+		m.mu.Unlock()
+		return Batch{}
+	}
+	run.cancel = cancel
+	items := len(run.batch.Items)
+	m.mu.Unlock()
+
+	fmt.Printf("📦 batch %s 开始处理，共 %d 条\n", id, items)
+	for i := 0; i < items; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		m.mu.Lock()
+		item := run.batch.Items[i]
+		headless, dir := run.batch.Headless, run.batch.Dir
+		if item.Status == BatchItemDone || item.Status == BatchItemFailed {
+			m.mu.Unlock()
+			continue
+		}
+		run.batch.Items[i].Status = BatchItemRunning
+		m.saveLocked(run)
+		m.mu.Unlock()
+
+		fmt.Printf("📦 batch %s [%d/%d] promptLen=%d image=%s\n", id, i+1, items, len(item.Prompt), item.Image)
+		item = runBatchItem(ctx, item, dir, headless)
+
+		m.mu.Lock()
+		if ctx.Err() != nil && !run.cancelled {
+			// 进程退出等原因中断：保持 pending，重启后继续
+			item.Status = BatchItemPending
+		}
+		run.batch.Items[i] = item
+		m.saveLocked(run)
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case run.cancelled:
+		run.batch.Status = BatchCancelled
+	case ctx.Err() != nil:
+		return run.batch.clone()
+	default:
+		run.batch.Status = BatchCompleted
+	}
+	now := time.Now()
+	run.batch.FinishedAt = &now
+	m.saveLocked(run)
+	if err := writeJSONFile(filepath.Join(run.batch.Dir, batchReportFile), run.batch); err != nil {
+		fmt.Printf("⚠️ batch %s 写入报告失败: %v\n", id, err)
+	}
+	fmt.Printf("📦 batch %s %s：成功 %d，失败 %d，图片 %d\n", id, run.batch.Status, run.batch.Summary.Done, run.batch.Summary.Failed, run.batch.Summary.Images)
+	return run.batch.clone()
+}
+
+func runBatchItem(ctx context.Context, item BatchItem, dir string, headless bool) BatchItem {
+	opts := DefaultRunOptions()
+	opts.PromptText = item.Prompt
+	opts.DownloadDir = dir
+	opts.Headless = headless
+	if item.Resolution != "" {
+		opts.OutputRes = item.Resolution
+	}
+	if item.Temperature > 0 {
+		opts.Temperature = item.Temperature
+	}
+	if item.Count > 0 {
+		opts.ScenarioCount = item.Count
+	}
+	if item.Image != "" {
+		src, err := resolveBatchImage(ctx, item, dir)
+		local := src
+		if err == nil {
+			local, err = prepareImageForRun(src)
+		}
+		if err != nil {
+			item.Status = BatchItemFailed
+			item.Error = fmt.Sprintf("准备图片失败: %v", err)
+			return item
+		}
+		if local != src {
+			// 压缩/转换后的临时文件，条目结束后删除
+			defer os.Remove(local)
+		}
+		opts.ImagePaths = []string{local}
+	}
+
+	var imagesOrig []string
+	if item.Image != "" {
+		imagesOrig = []string{item.Image}
+	}
+	job := RunJob(ctx, opts, imagesOrig)
+	item.JobID = job.ID
+	item.Results = job.Results
+	item.Error = job.Error
+	// 场景全部被拦截或只回复文本时任务本身是 succeeded，按是否下载到图片判断条目结果
+	if downloadedImages(item.Results) > 0 {
+		item.Status = BatchItemDone
+	} else {
+		item.Status = BatchItemFailed
+		if item.Error == "" {
+			item.Error = "没有生成任何图片"
+		}
+	}
+	return item
+}
+
+// resolveBatchImage 返回条目图片的本地路径；http(s) 地址会下载到批次目录的 inputs/ 下（已下载则复用）。
+func resolveBatchImage(ctx context.Context, item BatchItem, dir string) (string, error) {
+	if !strings.HasPrefix(item.Image, "http://") && !strings.HasPrefix(item.Image, "https://") {
+		if _, err := os.Stat(item.Image); err != nil {
+			return "", err
+		}
+		return item.Image, nil
+	}
+	inputs := filepath.Join(dir, "inputs")
+	base := sanitizeSegment(path.Base(strings.SplitN(item.Image, "?", 2)[0]))
+	target := filepath.Join(inputs, fmt.Sprintf("%03d-%s", item.Index, base))
+	if _, err := os.Stat(target); err == nil {
+		return target, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, item.Image, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("下载图片 HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBatchImageBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxBatchImageBytes {
+		return "", fmt.Errorf("图片超过 %d MB", maxBatchImageBytes>>20)
+	}
+	if filepath.Ext(target) == "" {
+		if exts, _ := mime.ExtensionsByType(resp.Header.Get("Content-Type")); len(exts) > 0 {
+			target += exts[0]
+		}
+	}
+	if err := os.MkdirAll(inputs, 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(target, data, 0o644); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+func (m *batchManager) saveLocked(run *batchRun) {
+	run.batch.UpdatedAt = time.Now()
+	run.batch.summarize()
+	if err := writeJSONFile(filepath.Join(run.batch.Dir, batchStateFile), run.batch); err != nil {
+		fmt.Printf("⚠️ batch %s 保存状态失败: %v\n", run.batch.ID, err)
+	}
+}
+
+func (m *batchManager) get(id string) (Batch, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run, ok := m.batches[id]
+	if !ok {
+		return Batch{}, false
+	}
+	return run.batch.clone(), true
+}
+
+// list 按创建时间倒序返回批次（不含条目明细）。
+func (m *batchManager) list() []Batch {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Batch, 0, len(m.batches))
+	for _, run := range m.batches {
+		b := run.batch
+		b.Items = nil
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+	return out
+}
+
+func (m *batchManager) cancel(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run, ok := m.batches[id]
+	if !ok || run.batch.Status != BatchRunning || run.cancel == nil {
+		return false
+	}
+	run.cancelled = true
+	run.cancel()
+	return true
+}
+
+// load 从磁盘读取批次状态并登记；执行中断时处于 running 的条目重置为 pending。
+func (m *batchManager) load(id string) (Batch, error) {
+	data, err := os.ReadFile(filepath.Join(batchesDir(), id, batchStateFile))
+	if err != nil {
+		return Batch{}, err
+	}
+	var b Batch
+	if err := json.Unmarshal(data, &b); err != nil {
+		return Batch{}, fmt.Errorf("解析批次状态失败: %w", err)
+	}
+	for i := range b.Items {
+		if b.Items[i].Status == BatchItemRunning {
+			b.Items[i].Status = BatchItemPending
+		}
+	}
+	b.summarize()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if run, ok := m.batches[b.ID]; ok {
+		return run.batch.clone(), nil
+	}
+	m.batches[b.ID] = &batchRun{batch: b}
+	return b.clone(), nil
+}
+
+// ResumeBatches 登记磁盘上的所有批次，并在后台继续执行上次未完成的批次。
+func ResumeBatches(ctx context.Context) {
+	entries, err := os.ReadDir(batchesDir())
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		b, err := batches.load(e.Name())
+		if err != nil {
+			continue
+		}
+		if b.Status == BatchRunning {
+			fmt.Printf("📦 恢复未完成的 batch %s（剩余 %d 条）\n", b.ID, b.Summary.Pending)
+			go batches.run(ctx, b.ID)
+		}
+	}
+}
+
+// RunBatch 同步执行一个清单文件，返回最终批次（含结果报告）。
+func RunBatch(ctx context.Context, manifestPath string, headless bool) (Batch, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return Batch{}, err
+	}
+	ext := strings.ToLower(filepath.Ext(manifestPath))
+	items, err := parseManifest(data, ext, filepath.Dir(manifestPath))
+	if err != nil {
+		return Batch{}, err
+	}
+	b, err := batches.create(items, data, ext, headless)
+	if err != nil {
+		return Batch{}, err
+	}
+	fmt.Printf("📦 batch %s 已创建，目录 %s\n", b.ID, b.Dir)
+	return batches.run(ctx, b.ID), nil
+}
+
+// ResumeBatch 同步继续执行一个已存在的批次。
+func ResumeBatch(ctx context.Context, id string) (Batch, error) {
+	b, err := batches.load(id)
+	if err != nil {
+		return Batch{}, err
+	}
+	if b.Status != BatchRunning {
+		return b, nil
+	}
+	return batches.run(ctx, id), nil
+}
+
+// parseManifest 解析 JSONL 或带表头的 CSV 清单（列：prompt,image,resolution,temperature,count）。
+// ext 为 .csv 时按 CSV 解析，否则按内容首字符判断。相对图片路径以 baseDir 为基准。
+func parseManifest(data []byte, ext, baseDir string) ([]BatchItem, error) {
+	content := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	var (
+		items []BatchItem
+		err   error
+	)
+	if ext == ".csv" || (len(content) > 0 && content[0] != '{') {
+		items, err = parseManifestCSV(content)
+	} else {
+		items, err = parseManifestJSONL(content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		it := &items[i]
+		it.Index = i + 1
+		it.Status = BatchItemPending
+		it.Prompt = strings.TrimSpace(it.Prompt)
+		it.Image = strings.TrimSpace(it.Image)
+		it.Resolution = strings.TrimSpace(it.Resolution)
+		if it.Prompt == "" {
+			return nil, fmt.Errorf("第 %d 条: prompt 不能为空", it.Index)
+		}
+		if it.Temperature < 0 || it.Temperature > 2 {
+			return nil, fmt.Errorf("第 %d 条: temperature 需在 0 到 2 之间", it.Index)
+		}
+		if it.Count < 0 {
+			return nil, fmt.Errorf("第 %d 条: count 不能为负数", it.Index)
+		}
+		if it.Image != "" && !strings.Contains(it.Image, "://") && !filepath.IsAbs(it.Image) && baseDir != "" {
+			it.Image = filepath.Join(baseDir, it.Image)
+		}
+	}
+	return items, nil
+}
+
+func parseManifestJSONL(content []byte) ([]BatchItem, error) {
+	var items []BatchItem
+	for n, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var it BatchItem
+		if err := json.Unmarshal([]byte(line), &it); err != nil {
+			return nil, fmt.Errorf("第 %d 行 JSON 无效: %w", n+1, err)
+		}
+		items = append(items, BatchItem{Prompt: it.Prompt, Image: it.Image, Resolution: it.Resolution, Temperature: it.Temperature, Count: it.Count})
+	}
+	return items, nil
+}
+
+func parseManifestCSV(content []byte) ([]BatchItem, error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV 无效: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	cols := map[string]int{}
+	for i, h := range rows[0] {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := cols["prompt"]; !ok {
+		return nil, errors.New("CSV 表头缺少 prompt 列")
+	}
+	var items []BatchItem
+	for n, row := range rows[1:] {
+		get := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		it := BatchItem{Prompt: get("prompt"), Image: get("image"), Resolution: get("resolution")}
+		if v := get("temperature"); v != "" {
+			if it.Temperature, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil, fmt.Errorf("第 %d 行 temperature 无效: %q", n+2, v)
+			}
+		}
+		if v := get("count"); v != "" {
+			if it.Count, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("第 %d 行 count 无效: %q", n+2, v)
+			}
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func handleCreateBatch(w http.ResponseWriter, r *http.Request) {
+	var (
+		data []byte
+		ext  string
+		err  error
+	)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("parse form: %v", err)})
+			return
+		}
+		file, header, err := r.FormFile("manifest")
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("读取 manifest 文件字段失败: %v", err)})
+			return
+		}
+		defer file.Close()
+		ext = strings.ToLower(filepath.Ext(header.Filename))
+		data, err = io.ReadAll(file)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("read manifest: %v", err)})
+			return
+		}
+	} else {
+		data, err = io.ReadAll(r.Body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("read body: %v", err)})
+			return
+		}
+		ext = ".jsonl"
+		if strings.Contains(r.Header.Get("Content-Type"), "csv") || r.URL.Query().Get("format") == "csv" {
+			ext = ".csv"
+		}
+	}
+	if ext != ".csv" {
+		ext = ".jsonl"
+	}
+	headless := r.URL.Query().Get("headful") == ""
+	items, err := parseManifest(data, ext, "")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("清单无效: %v", err)})
+		return
+	}
+	b, err := batches.create(items, data, ext, headless)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	go batches.run(context.Background(), b.ID)
+	fmt.Printf("📥 /batches queued batch=%s items=%d\n", b.ID, len(b.Items))
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"status":  b.Status,
+		"batchId": b.ID,
+		"batch":   b,
+	})
+}
+
+func handleListBatches(w http.ResponseWriter, r *http.Request) {
+	list := batches.list()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"count":   len(list),
+		"batches": list,
+	})
+}
+
+func handleGetBatch(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.PathValue("id"))
+	b, ok := batches.get(id)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("batch %s 不存在", id)})
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+func handleCancelBatch(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.PathValue("id"))
+	if !batches.cancel(id) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("batch %s 不存在或已结束", id)})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "cancelled", "batchId": id})
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	playwright "github.com/playwright-community/playwright-go"
+)
+
+const browserHealthInterval = 30 * time.Second
+
+var browserPool = newBrowserManager(settings.Runner.BrowserPoolSize)
+
+// browserManager 在进程内共享 Playwright driver 与 Chromium：driver 只启动一次，
+// 每种 headless 模式保留 size 个浏览器，按轮询为场景创建独立的 BrowserContext，
+// 浏览器断开后在下次使用或健康检查时重新启动。
+type browserManager struct {
+	mu       sync.Mutex
+	size     int
+	pw       *playwright.Playwright
+	browsers map[bool][]playwright.Browser
+	next     map[bool]int
+	health   sync.Once
+	stopped  chan struct{}
+}
+
+func newBrowserManager(size int) *browserManager {
+	if size < 1 {
+		size = 1
+	}
+	return &browserManager{
+		size:     size,
+		browsers: map[bool][]playwright.Browser{},
+		next:     map[bool]int{},
+		stopped:  make(chan struct{}),
+	}
+}
+
+func (m *browserManager) engine() string {
+	return "chromium"
+}
+
+// newContext 从池中选一个浏览器创建 BrowserContext；浏览器已断开时重启后重试一次。
+func (m *browserManager) newContext(headless bool, opts playwright.BrowserNewContextOptions) (playwright.BrowserContext, error) {
+	m.health.Do(func() { go m.healthLoop() })
+
+	m.mu.Lock()
+	idx := m.next[headless] % m.size
+	m.next[headless] = idx + 1
+	browser, err := m.browserLocked(headless, idx)
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	bctx, err := browser.NewContext(opts)
+	if err == nil || browser.IsConnected() {
+		return bctx, err
+	}
+	fmt.Printf("⚠️ 浏览器已断开(headless=%v #%d)，重新启动：%v\n", headless, idx, err)
+	m.mu.Lock()
+	browser, err = m.browserLocked(headless, idx)
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return browser.NewContext(opts)
+}
+
+func (m *browserManager) browserLocked(headless bool, idx int) (playwright.Browser, error) {
+	select {
+	case <-m.stopped:
+		return nil, fmt.Errorf("browser pool closed")
+	default:
+	}
+	if m.pw == nil {
+		pw, err := playwright.Run()
+		if err != nil {
+			return nil, fmt.Errorf("start playwright: %w", err)
+		}
+		m.pw = pw
+	}
+	slots := m.browsers[headless]
+	if slots == nil {
+		slots = make([]playwright.Browser, m.size)
+		m.browsers[headless] = slots
+	}
+	if b := slots[idx]; b != nil && b.IsConnected() {
+		return b, nil
+	}
+	b, err := m.pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(headless),
+		Args:     settings.Runner.ChromiumArgs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("launch browser: %w", err)
+	}
+	b.OnDisconnected(func(playwright.Browser) {
+		fmt.Printf("⚠️ 浏览器断开连接(headless=%v #%d)\n", headless, idx)
+	})
+	slots[idx] = b
+	fmt.Printf("🌐 浏览器已启动(headless=%v #%d)\n", headless, idx)
+	return b, nil
+}
+
+// healthLoop 定期检查已启动的浏览器，断开的立即重启，避免下一个场景承担启动耗时。
+func (m *browserManager) healthLoop() {
+	ticker := time.NewTicker(browserHealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stopped:
+			return
+		case <-ticker.C:
+		}
+		m.mu.Lock()
+		for headless, slots := range m.browsers {
+			for idx, b := range slots {
+				if b == nil || b.IsConnected() {
+					continue
+				}
+				if _, err := m.browserLocked(headless, idx); err != nil {
+					fmt.Printf("⚠️ 浏览器重启失败(headless=%v #%d)：%v\n", headless, idx, err)
+				}
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+// close 关闭所有浏览器并停止 driver，之后不可再使用。
+func (m *browserManager) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.stopped:
+		return
+	default:
+		close(m.stopped)
+	}
+	for _, slots := range m.browsers {
+		for _, b := range slots {
+			if b != nil {
+				_ = b.Close()
+			}
+		}
+	}
+	m.browsers = map[bool][]playwright.Browser{}
+	if m.pw != nil {
+		_ = m.pw.Stop()
+		m.pw = nil
+	}
+}
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"path/filepath"
+
+	"vertex-nano-banana-unlimited/internal/config"
+	"vertex-nano-banana-unlimited/internal/history"
+	"vertex-nano-banana-unlimited/internal/proxy"
+	"vertex-nano-banana-unlimited/internal/selectors"
+	"vertex-nano-banana-unlimited/internal/steps"
+)
+
+// settings 是当前生效的配置；未调用 Configure 时使用默认值。
+var settings = config.Default()
+
+// selectorRegistry 是步骤使用的页面定位器；由 Configure 按 runner.selectorsFile 加载。
+var selectorRegistry *selectors.Registry
+
+// Configure 应用配置并重建依赖配置的共享组件，需在启动服务或首次运行之前调用。
+func Configure(cfg config.Config) error {
+	reg, err := selectors.New(cfg.Runner.SelectorsFile)
+	if err != nil {
+		return err
+	}
+	settings = cfg
+	runScheduler = newScheduler(cfg.Runner.MaxContexts)
+	browserPool = newBrowserManager(cfg.Runner.BrowserPoolSize)
+	runHistory = history.Open(filepath.Join(cfg.Runner.DownloadDir, "history.jsonl"))
+	proxy.Configure(cfg.Proxy)
+	selectorRegistry = reg
+	steps.UseSelectors(reg)
+	return nil
+}
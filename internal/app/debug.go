@@ -0,0 +1,136 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	playwright "github.com/playwright-community/playwright-go"
+)
+
+// debugMaxLines 限制每类日志保留的行数，避免长时间运行的页面占用过多内存。
+const debugMaxLines = 500
+
+// debugRecorder 在场景运行期间收集控制台消息、页面错误与失败请求，
+// 场景失败时连同截图和 HTML 一起写成调试包。
+type debugRecorder struct {
+	mu       sync.Mutex
+	page     playwright.Page
+	console  []string
+	network  []string
+	lastStep string
+}
+
+func newDebugRecorder(page playwright.Page) *debugRecorder {
+	d := &debugRecorder{page: page}
+	page.OnConsole(func(msg playwright.ConsoleMessage) {
+		line := fmt.Sprintf("%s [%s] %s", time.Now().Format(time.RFC3339), msg.Type(), msg.Text())
+		if loc := msg.Location(); loc != nil && loc.URL != "" {
+			line += fmt.Sprintf(" (%s:%d:%d)", loc.URL, loc.LineNumber+1, loc.ColumnNumber+1)
+		}
+		d.add(&d.console, line)
+	})
+	page.OnPageError(func(err error) {
+		d.add(&d.console, fmt.Sprintf("%s [pageerror] %v", time.Now().Format(time.RFC3339), err))
+	})
+	page.OnRequestFailed(func(req playwright.Request) {
+		d.add(&d.network, fmt.Sprintf("%s %s %s (%s): %v", time.Now().Format(time.RFC3339), req.Method(), req.URL(), req.ResourceType(), req.Failure()))
+	})
+	return d
+}
+
+func (d *debugRecorder) add(dst *[]string, line string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(*dst) >= debugMaxLines {
+		*dst = (*dst)[1:]
+	}
+	*dst = append(*dst, line)
+}
+
+// step 记录最近开始的步骤名。
+func (d *debugRecorder) step(name string) {
+	d.mu.Lock()
+	d.lastStep = name
+	d.mu.Unlock()
+}
+
+// debugBundle 是调试包目录下 bundle.json 的内容。
+type debugBundle struct {
+	ScenarioID int       `json:"scenarioId"`
+	ProxyTag   string    `json:"proxyTag,omitempty"`
+	PageURL    string    `json:"pageUrl"`
+	LastStep   string    `json:"lastStep,omitempty"`
+	FailedStep string    `json:"failedStep,omitempty"`
+	Attempts   int       `json:"attempts,omitempty"`
+	Reason     string    `json:"reason"`
+	Error      string    `json:"error,omitempty"`
+	Files      []string  `json:"files"`
+	Time       time.Time `json:"time"`
+}
+
+// save 把调试包写入 dir 并返回目录路径；单个文件失败不影响其余文件。
+func (d *debugRecorder) save(dir string, res ScenarioResult, reason string, cause error) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("make debug dir: %w", err)
+	}
+	d.mu.Lock()
+	console := strings.Join(d.console, "\n")
+	network := strings.Join(d.network, "\n")
+	bundle := debugBundle{
+		ScenarioID: res.ID,
+		ProxyTag:   res.ProxyTag,
+		PageURL:    d.page.URL(),
+		LastStep:   d.lastStep,
+		FailedStep: res.FailedStep,
+		Attempts:   res.Attempts,
+		Reason:     reason,
+		Time:       time.Now(),
+	}
+	d.mu.Unlock()
+	if cause != nil {
+		bundle.Error = cause.Error()
+	}
+
+	write := func(name string, data []byte) {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			fmt.Printf("⚠️ 写入调试文件 %s 失败: %v\n", name, err)
+			return
+		}
+		bundle.Files = append(bundle.Files, name)
+	}
+	if shot, err := d.page.Screenshot(playwright.PageScreenshotOptions{
+		FullPage: playwright.Bool(true),
+		Timeout:  playwright.Float(10000),
+	}); err == nil {
+		write("screenshot.png", shot)
+	} else {
+		fmt.Printf("⚠️ 调试截图失败: %v\n", err)
+	}
+	if html, err := d.page.Content(); err == nil {
+		write("page.html", []byte(html))
+	} else {
+		fmt.Printf("⚠️ 读取页面 HTML 失败: %v\n", err)
+	}
+	write("console.log", []byte(console))
+	write("network.log", []byte(network))
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bundle.json"), data, 0o644); err != nil {
+		return "", fmt.Errorf("write bundle.json: %w", err)
+	}
+	return dir, nil
+}
+
+// artifactDir 返回场景附属文件（调试包、trace）的目录；放在 kind 子目录下，不会出现在图库中。
+func artifactDir(opts RunOptions, kind, batchFolder string, id int) string {
+	name := fmt.Sprintf("%s-%s-s%d", time.Now().Format("20060102-150405"), batchFolder, id)
+	return filepath.Join(opts.DownloadDir, kind, name)
+}
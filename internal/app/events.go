@@ -0,0 +1,104 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type StepPhase string
+
+const (
+	StepStarted   StepPhase = "start"
+	StepSucceeded StepPhase = "success"
+	StepFailed    StepPhase = "failure"
+	StepSkipped   StepPhase = "skipped"
+	StepRetrying  StepPhase = "retry"
+)
+
+// StepEvent 描述场景中某一步骤的进度，用于 SSE 推送。
+type StepEvent struct {
+	JobID      string    `json:"jobId,omitempty"`
+	ScenarioID int       `json:"scenarioId"`
+	ProxyTag   string    `json:"proxyTag,omitempty"`
+	Step       string    `json:"step"`
+	Phase      StepPhase `json:"phase"`
+	Attempts   int       `json:"attempts,omitempty"`
+	DurationMs int64     `json:"durationMs,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+func (o RunOptions) emit(ev StepEvent) {
+	if o.OnEvent == nil {
+		return
+	}
+	ev.Time = time.Now()
+	o.OnEvent(ev)
+}
+
+// sseKeepAlive SSE 空闲时发送注释行的间隔，避免代理或浏览器断开长连接。
+const sseKeepAlive = 15 * time.Second
+
+// handleJobEvents 以 SSE 推送任务的步骤事件：先回放已有事件，再实时推送，任务结束后发送 done 并关闭。
+func handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.PathValue("id"))
+	if _, ok := jobs.get(id); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("job %s 不存在", id)})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming unsupported"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	sent := 0
+	var lastStatus JobStatus
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		job, events, notify, ok := jobs.eventsSince(id, sent)
+		if !ok {
+			return
+		}
+		for _, ev := range events {
+			writeSSE(w, "step", ev)
+		}
+		sent += len(events)
+		if job.Status != lastStatus {
+			lastStatus = job.Status
+			writeSSE(w, "status", job)
+		}
+		if job.done() {
+			writeSSE(w, "done", job)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-notify:
+		case <-keepAlive.C:
+			_, _ = fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, event string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"vertex-nano-banana-unlimited/internal/history"
+)
+
+var runHistory = history.Open(filepath.Join(settings.Runner.DownloadDir, "history.jsonl"))
+
+// recordJob 将已结束任务的每个场景结果写入历史；没有任何场景结果时记录一条任务级失败。
+func recordJob(job Job) {
+	base := history.Record{
+		JobID:          job.ID,
+		JobStatus:      string(job.Status),
+		Prompt:         job.Prompt,
+		Model:          job.Model,
+		ImageOrig:      job.ImageOrig,
+		ImageUsed:      job.ImageUsed,
+		ImagesOrig:     job.ImagesOrig,
+		ImagesUsed:     job.ImagesUsed,
+		OutputRes:      job.OutputRes,
+		Temperature:    job.Temperature,
+		AspectRatio:    job.AspectRatio,
+		CandidateCount: job.CandidateCount,
+		Error:          job.Error,
+		CreatedAt:      job.CreatedAt,
+	}
+	if job.FinishedAt != nil {
+		base.FinishedAt = *job.FinishedAt
+	}
+	var records []history.Record
+	for _, r := range job.Results {
+		rec := base
+		rec.ScenarioID = r.ID
+		rec.ProxyTag = r.ProxyTag
+		if r.Model != "" {
+			rec.Model = r.Model
+		}
+		rec.Outcome = string(r.Outcome)
+		rec.Path = r.Path
+		rec.Paths = r.Paths
+		rec.URL = r.URL
+		rec.Error = r.Error
+		rec.FailedStep = r.FailedStep
+		rec.Attempts = r.Attempts
+		rec.DebugURL = r.DebugURL
+		rec.TraceURL = r.TraceURL
+		rec.Text = r.Text
+		rec.BlockReason = r.BlockReason
+		if r.OutputRes != "" {
+			rec.OutputRes = r.OutputRes
+		}
+		records = append(records, rec)
+	}
+	if len(records) == 0 {
+		records = append(records, base)
+	}
+	if err := runHistory.Append(records...); err != nil {
+		fmt.Printf("⚠️ 写入历史记录失败(job=%s): %v\n", job.ID, err)
+	}
+}
+
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := history.Filter{
+		JobID:    strings.TrimSpace(q.Get("jobId")),
+		Prompt:   strings.TrimSpace(q.Get("prompt")),
+		Outcome:  strings.TrimSpace(q.Get("outcome")),
+		ProxyTag: strings.TrimSpace(q.Get("proxyTag")),
+		Path:     strings.TrimPrefix(strings.TrimSpace(q.Get("path")), "/"),
+	}
+	for key, dst := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if v := strings.TrimSpace(q.Get(key)); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("%s 无效: %s", key, v)})
+				return
+			}
+			*dst = n
+		}
+	}
+	for key, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := strings.TrimSpace(q.Get(key)); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("%s 需为 RFC3339 时间: %v", key, err)})
+				return
+			}
+			*dst = t
+		}
+	}
+	page, err := runHistory.Query(filter)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("query history: %v", err)})
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
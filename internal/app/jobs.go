@@ -0,0 +1,303 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"vertex-nano-banana-unlimited/internal/steps"
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// maxJobHistory 内存中保留的最近任务数量，超出后丢弃最旧的已结束任务。
+const maxJobHistory = 100
+
+// Job 描述一次 /run 请求及其执行状态。
+type Job struct {
+	ID        string    `json:"id"`
+	Status    JobStatus `json:"status"`
+	Prompt    string    `json:"prompt"`
+	Model     string    `json:"model,omitempty"`
+	ImageOrig string    `json:"imageOrig,omitempty"`
+	ImageUsed string    `json:"imageUsed,omitempty"`
+	// ImagesOrig/ImagesUsed 列出全部参考图片，ImageOrig/ImageUsed 为第一张（兼容单图客户端）。
+	ImagesOrig     []string         `json:"imagesOrig,omitempty"`
+	ImagesUsed     []string         `json:"imagesUsed,omitempty"`
+	ScenarioCount  int              `json:"scenarioCount"`
+	TargetImages   int              `json:"targetImages,omitempty"`
+	OutputRes      string           `json:"outputRes"`
+	Temperature    float64          `json:"temperature"`
+	AspectRatio    string           `json:"aspectRatio,omitempty"`
+	CandidateCount int              `json:"candidateCount,omitempty"`
+	Results        []ScenarioResult `json:"results"`
+	Error          string           `json:"error,omitempty"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	StartedAt      *time.Time       `json:"startedAt,omitempty"`
+	FinishedAt     *time.Time       `json:"finishedAt,omitempty"`
+}
+
+func (j *Job) done() bool {
+	switch j.Status {
+	case JobSucceeded, JobFailed, JobCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Downloaded 返回任务实际下载的图片数。场景全部被拦截或只回复文本时任务仍是 succeeded，但这里为 0。
+func (j Job) Downloaded() int {
+	return downloadedImages(j.Results)
+}
+
+// downloadedImages 统计结果中成功下载的图片数。
+func downloadedImages(results []ScenarioResult) int {
+	n := 0
+	for _, r := range results {
+		if r.Outcome == steps.DownloadOutcomeDownloaded {
+			n += max(len(r.Paths), 1)
+		}
+	}
+	return n
+}
+
+// jobEntry 保存任务快照及其步骤事件日志；notify 在每次变更时关闭并替换，用于唤醒 SSE 订阅者。
+type jobEntry struct {
+	job    Job
+	events []StepEvent
+	notify chan struct{}
+	cancel context.CancelFunc
+}
+
+func (e *jobEntry) changed() {
+	close(e.notify)
+	e.notify = make(chan struct{})
+}
+
+type jobManager struct {
+	mu    sync.Mutex
+	jobs  map[string]*jobEntry
+	order []string
+}
+
+var jobs = newJobManager()
+
+func newJobManager() *jobManager {
+	return &jobManager{jobs: map[string]*jobEntry{}}
+}
+
+// submit 登记任务并在后台执行，立即返回任务快照。cleanup 在任务结束后调用（可为 nil）。
+func (m *jobManager) submit(opts RunOptions, imagesOrig []string, cleanup func()) Job {
+	job, ctx, opts := m.register(context.Background(), opts, imagesOrig)
+	go func() {
+		if cleanup != nil {
+			defer cleanup()
+		}
+		m.execute(ctx, job.ID, opts)
+	}()
+	return job
+}
+
+// RunJob 同步执行一次运行：与 /run 一样登记为任务并写入历史，返回结束后的任务快照。
+func RunJob(ctx context.Context, opts RunOptions, imagesOrig []string) Job {
+	job, jctx, opts := jobs.register(ctx, opts, imagesOrig)
+	jobs.execute(jctx, job.ID, opts)
+	job, _ = jobs.get(job.ID)
+	return job
+}
+
+func (m *jobManager) register(parent context.Context, opts RunOptions, imagesOrig []string) (Job, context.Context, RunOptions) {
+	job := Job{
+		ID:             newJobID(),
+		Status:         JobQueued,
+		Prompt:         opts.PromptText,
+		Model:          opts.Model,
+		ImagesOrig:     imagesOrig,
+		ImagesUsed:     opts.ImagePaths,
+		ScenarioCount:  opts.ScenarioCount,
+		TargetImages:   opts.TargetImages,
+		OutputRes:      opts.OutputRes,
+		Temperature:    opts.Temperature,
+		AspectRatio:    opts.AspectRatio,
+		CandidateCount: opts.CandidateCount,
+		CreatedAt:      time.Now(),
+	}
+	if len(imagesOrig) > 0 {
+		job.ImageOrig = imagesOrig[0]
+	}
+	if len(opts.ImagePaths) > 0 {
+		job.ImageUsed = opts.ImagePaths[0]
+	}
+
+	onEvent := opts.OnEvent
+	opts.OnEvent = func(ev StepEvent) {
+		ev.JobID = job.ID
+		m.appendEvent(job.ID, ev)
+		// 首个场景拿到运行槽位时任务才真正开始，此前保持 queued
+		if ev.Step == stepAcquireSlot && ev.Phase == StepSucceeded {
+			m.markRunning(job.ID)
+		}
+		if onEvent != nil {
+			onEvent(ev)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	m.mu.Lock()
+	m.jobs[job.ID] = &jobEntry{job: job, notify: make(chan struct{}), cancel: cancel}
+	m.order = append(m.order, job.ID)
+	m.trimLocked()
+	m.mu.Unlock()
+	return job, ctx, opts
+}
+
+func (m *jobManager) execute(ctx context.Context, id string, opts RunOptions) {
+	results, err := RunWithOptions(ctx, opts)
+	m.finish(id, results, err)
+	m.mu.Lock()
+	if e, ok := m.jobs[id]; ok {
+		e.cancel()
+	}
+	m.mu.Unlock()
+}
+
+func (m *jobManager) markRunning(id string) {
+	m.update(id, func(j *Job) {
+		if j.Status != JobQueued {
+			return
+		}
+		now := time.Now()
+		j.Status = JobRunning
+		j.StartedAt = &now
+	})
+}
+
+// cancel 取消指定任务。found 为 false 表示任务不存在；cancelled 为 false 表示任务已经结束。
+func (m *jobManager) cancel(id string) (found, cancelled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return false, false
+	}
+	if e.job.done() {
+		return true, false
+	}
+	e.cancel()
+	return true, true
+}
+
+func (m *jobManager) finish(id string, results []ScenarioResult, err error) {
+	m.update(id, func(j *Job) {
+		now := time.Now()
+		j.FinishedAt = &now
+		j.Results = results
+		switch {
+		case err == nil:
+			j.Status = JobSucceeded
+		case errors.Is(err, context.Canceled):
+			j.Status = JobCancelled
+			j.Error = "cancelled"
+		default:
+			j.Status = JobFailed
+			j.Error = err.Error()
+		}
+		fmt.Printf("🏁 job %s %s results=%d\n", j.ID, j.Status, len(j.Results))
+	})
+	if job, ok := m.get(id); ok {
+		recordJob(job)
+	}
+}
+
+func (m *jobManager) update(id string, fn func(*Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.jobs[id]; ok {
+		fn(&e.job)
+		e.changed()
+	}
+}
+
+func (m *jobManager) appendEvent(id string, ev StepEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.jobs[id]; ok {
+		e.events = append(e.events, ev)
+		e.changed()
+	}
+}
+
+func (m *jobManager) get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return e.job, true
+}
+
+// eventsSince 返回任务快照、from 之后的事件以及下一次变更的通知通道。
+func (m *jobManager) eventsSince(id string, from int) (Job, []StepEvent, <-chan struct{}, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, nil, nil, false
+	}
+	var events []StepEvent
+	if from < len(e.events) {
+		events = append(events, e.events[from:]...)
+	}
+	return e.job, events, e.notify, true
+}
+
+// list 按创建时间倒序返回最近的任务。
+func (m *jobManager) list(limit int) []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Job, 0, len(m.order))
+	for i := len(m.order) - 1; i >= 0; i-- {
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+		out = append(out, m.jobs[m.order[i]].job)
+	}
+	return out
+}
+
+func (m *jobManager) trimLocked() {
+	for len(m.order) > maxJobHistory {
+		idx := -1
+		for i, id := range m.order {
+			if m.jobs[id].job.done() {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return
+		}
+		delete(m.jobs, m.order[idx])
+		m.order = append(m.order[:idx], m.order[idx+1:]...)
+	}
+}
+
+func newJobID() string {
+	buf := make([]byte, 3)
+	_, _ = rand.Read(buf)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,15 @@
+package app
+
+import "vertex-nano-banana-unlimited/internal/steps"
+
+// outcomeMessages 是各失败结果写入 ScenarioResult.Error 的说明。
+var outcomeMessages = map[steps.DownloadOutcome]string{
+	steps.DownloadOutcomeExhausted:    "resource exhausted (429/quota)",
+	steps.DownloadOutcomeDeadline:     "generation deadline expired",
+	steps.DownloadOutcomeTimeout:      "no result within the download wait",
+	steps.DownloadOutcomeCancelled:    "operation cancelled by server",
+	steps.DownloadOutcomeSubmitFailed: "prompt submission failed",
+	steps.DownloadOutcomeTokenInvalid: "recaptcha token invalid",
+	steps.DownloadOutcomeBlocked:      "content blocked by safety filters",
+	steps.DownloadOutcomeText:         "model replied without an image",
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	playwright "github.com/playwright-community/playwright-go"
+
+	"vertex-nano-banana-unlimited/internal/steps"
+)
+
+// 按名称引用的步骤：/selectors/test 挑选页面准备步骤，runScenario 在提交前开始识别生成请求。
+const (
+	stepNavigate      = "Navigate to studio"
+	stepAcceptTerms   = "Accept terms dialog"
+	stepAcceptCookies = "Accept cookies bar"
+	stepOpenSettings  = "Open model settings"
+	stepSubmit        = "Submit prompt"
+)
+
+// scenarioSteps 返回提交提示词之前的有序步骤。UI 偶发的 false 通过重试吸收，
+// 只有最终失败才会结束场景并冻结节点。
+func scenarioSteps(id int, opts RunOptions) []steps.Step {
+	return []steps.Step{
+		steps.Func{
+			StepName: stepNavigate,
+			// goto 与 networkidle 等待各自受 GotoTimeout 约束
+			StepTimeout: 2*opts.GotoTimeout + 5*time.Second,
+			Policy:      steps.RetryPolicy{Attempts: 2, Backoff: 2 * time.Second},
+			Do: func(page playwright.Page) (bool, error) {
+				if _, err := page.Goto(opts.TargetURL, playwright.PageGotoOptions{
+					WaitUntil: playwright.WaitUntilStateDomcontentloaded,
+					Timeout:   playwright.Float(float64(opts.GotoTimeout.Milliseconds())),
+				}); err != nil {
+					return false, err
+				}
+				fmt.Printf("✅ [%d] URL after goto: %s\n", id, page.URL())
+				_ = page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
+					State:   playwright.LoadStateNetworkidle,
+					Timeout: playwright.Float(float64(opts.GotoTimeout.Milliseconds())),
+				})
+				_ = page.BringToFront()
+				_ = page.Mouse().Click(5, 5)
+				_ = page.Keyboard().Press("Escape")
+				time.Sleep(opts.SubStepPause)
+				return true, nil
+			},
+		},
+		steps.Func{
+			StepName: stepAcceptTerms,
+			// AcceptTermsBlocking 内部已轮询，不再重试
+			StepTimeout: opts.TermsTimeout + 10*time.Second,
+			Do: func(page playwright.Page) (bool, error) {
+				return steps.AcceptTermsBlocking(page, opts.TermsTimeout)
+			},
+		},
+		steps.Func{
+			StepName:    stepAcceptCookies,
+			StepTimeout: 10 * time.Second,
+			Policy:      steps.RetryPolicy{Attempts: 2, Backoff: time.Second},
+			Pre:         func(page playwright.Page) (bool, error) { return steps.CookieBarVisible(page), nil },
+			Do:          steps.AcceptCookieBar,
+		},
+		steps.Func{
+			StepName:    stepOpenSettings,
+			StepTimeout: 10 * time.Second,
+			Policy:      steps.RetryPolicy{Attempts: 3, Backoff: time.Second},
+			// 面板已展开时再次点击会把它收起
+			Pre:  func(page playwright.Page) (bool, error) { return !steps.ModelSettingsOpen(page), nil },
+			Do:   steps.OpenModelSettings,
+			Post: waitVisible(steps.ModelSettingsOpen, 3*time.Second),
+		},
+		steps.Func{
+			StepName:    fmt.Sprintf("Set output resolution to %s", opts.OutputRes),
+			StepTimeout: 15 * time.Second,
+			Policy:      steps.RetryPolicy{Attempts: 3, Backoff: time.Second},
+			Do: func(page playwright.Page) (bool, error) {
+				return steps.SetOutputResolution(page, opts.OutputRes)
+			},
+		},
+		steps.Func{
+			StepName:    fmt.Sprintf("Set temperature to %.1f", opts.Temperature),
+			StepTimeout: 10 * time.Second,
+			Policy:      steps.RetryPolicy{Attempts: 2, Backoff: time.Second},
+			Pre:         func(playwright.Page) (bool, error) { return opts.Temperature > 0, nil },
+			Do: func(page playwright.Page) (bool, error) {
+				return steps.SetTemperature(page, opts.Temperature)
+			},
+		},
+		steps.Func{
+			StepName:    fmt.Sprintf("Set aspect ratio to %s", opts.AspectRatio),
+			StepTimeout: 15 * time.Second,
+			Policy:      steps.RetryPolicy{Attempts: 3, Backoff: time.Second},
+			Pre:         func(playwright.Page) (bool, error) { return opts.AspectRatio != "", nil },
+			Do: func(page playwright.Page) (bool, error) {
+				return steps.SetAspectRatio(page, opts.AspectRatio)
+			},
+		},
+		steps.Func{
+			StepName:    fmt.Sprintf("Set candidate count to %d", opts.CandidateCount),
+			StepTimeout: 15 * time.Second,
+			Policy:      steps.RetryPolicy{Attempts: 3, Backoff: time.Second},
+			Pre:         func(playwright.Page) (bool, error) { return opts.CandidateCount > 0, nil },
+			Do: func(page playwright.Page) (bool, error) {
+				return steps.SetCandidateCount(page, opts.CandidateCount)
+			},
+		},
+		steps.Func{
+			StepName:    "Enter prompt text",
+			StepTimeout: 15 * time.Second,
+			Policy:      steps.RetryPolicy{Attempts: 2, Backoff: time.Second},
+			Do: func(page playwright.Page) (bool, error) {
+				return steps.EnterPrompt(page, opts.PromptText)
+			},
+			Post: func(page playwright.Page) (bool, error) {
+				length := promptLength(page)
+				fmt.Printf("ℹ️ [%d] Prompt length after entry: %d chars\n", id, length)
+				return length > 0, nil
+			},
+		},
+		steps.Func{
+			StepName: fmt.Sprintf("Upload %d reference image(s)", len(opts.ImagePaths)),
+			// 每张图片最多等待约 30 秒；重试时跳过已附加的图片
+			StepTimeout: time.Duration(len(opts.ImagePaths)) * 30 * time.Second,
+			Policy:      steps.RetryPolicy{Attempts: 2, Backoff: 2 * time.Second},
+			Pre:         func(playwright.Page) (bool, error) { return len(opts.ImagePaths) > 0, nil },
+			Do: func(page playwright.Page) (bool, error) {
+				return steps.UploadLocalFiles(page, opts.ImagePaths)
+			},
+		},
+		steps.Func{
+			StepName:    stepSubmit,
+			StepTimeout: 10 * time.Second,
+			// 附件处理期间提交按钮会暂时禁用
+			Policy: steps.RetryPolicy{Attempts: 5, Backoff: 2 * time.Second},
+			Do:     steps.SubmitPrompt,
+		},
+	}
+}
+
+// pickSteps 按 names 的顺序从 all 中挑选步骤，缺少任何一个时返回错误。
+func pickSteps(all []steps.Step, names ...string) ([]steps.Step, error) {
+	out := make([]steps.Step, 0, len(names))
+	for _, name := range names {
+		i := slices.IndexFunc(all, func(s steps.Step) bool { return s.Name() == name })
+		if i < 0 {
+			return nil, fmt.Errorf("step %q not found", name)
+		}
+		out = append(out, all[i])
+	}
+	return out, nil
+}
+
+// waitVisible 在 within 内轮询 check，用作 UI 动画后的后置条件。
+func waitVisible(check func(playwright.Page) bool, within time.Duration) func(playwright.Page) (bool, error) {
+	return func(page playwright.Page) (bool, error) {
+		deadline := time.Now().Add(within)
+		for {
+			if check(page) {
+				return true, nil
+			}
+			if time.Now().After(deadline) {
+				return false, nil
+			}
+			time.Sleep(200 * time.Millisecond)
+		}
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
-	"sync"
 	"time"
 
 	playwright "github.com/playwright-community/playwright-go"
@@ -17,16 +19,31 @@ import (
 )
 
 type RunOptions struct {
-	TargetURL     string
-	ImagePath     string
+	TargetURL     string // 为空时由 Model 生成
+	Model         string
+	ImagePaths    []string // 按顺序上传的参考图片，为空时纯文本生成
 	PromptText    string
 	DownloadDir   string
 	Headless      bool
 	ScenarioCount int
 	StepPause     time.Duration
 	SubStepPause  time.Duration
+	GotoTimeout   time.Duration
+	TermsTimeout  time.Duration
+	DownloadWait  time.Duration
 	OutputRes     string
 	Temperature   float64
+	// AspectRatio（如 16:9）与 CandidateCount 为空/0 时保留页面默认值。
+	AspectRatio    string
+	CandidateCount int
+	// TargetImages > 0 时按成功出图数量运行：失败的场景会换用其他未冻结节点重试，
+	// 直到达到目标、节点耗尽或超过 TargetDeadline；此时 ScenarioCount 被忽略。
+	TargetImages   int
+	TargetDeadline time.Duration
+	// Trace 为每个场景录制 Playwright trace（含截图与 DOM 快照），保存为 trace.zip。
+	Trace bool
+	// OnEvent 接收每个步骤的开始/结束事件（可为空）。
+	OnEvent func(StepEvent)
 }
 
 type ScenarioResult struct {
@@ -36,36 +53,43 @@ type ScenarioResult struct {
 	URL       string                `json:"url"`
 	ProxyTag  string                `json:"proxyTag,omitempty"`
 	OutputRes string                `json:"outputRes,omitempty"`
+	Model     string                `json:"model,omitempty"`
 	Error     string                `json:"error,omitempty"`
+	// Paths/URLs 包含本场景保存的全部图片，Path/URL 为第一张。
+	Paths []string `json:"paths,omitempty"`
+	URLs  []string `json:"urls,omitempty"`
+	// FailedStep/Attempts 记录导致场景失败的步骤及其尝试次数。
+	FailedStep string `json:"failedStep,omitempty"`
+	Attempts   int    `json:"attempts,omitempty"`
+	// DebugURL 指向失败时保存的调试包目录（截图、HTML、控制台与网络日志）。
+	DebugURL string `json:"debugUrl,omitempty"`
+	// TraceURL 指向 trace.zip，可用 npx playwright show-trace 打开。
+	TraceURL string `json:"traceUrl,omitempty"`
+	// Text 是模型的文本回复（包括随图片返回的说明），BlockReason 是安全拦截原因。
+	Text        string `json:"text,omitempty"`
+	BlockReason string `json:"blockReason,omitempty"`
 }
 
 func DefaultRunOptions() RunOptions {
-	imagePath := "" // 不设置默认图片路径，要求用户上传
-
-	downloadDir := os.Getenv("DEFAULT_DOWNLOAD_DIR")
-	if downloadDir == "" {
-		downloadDir = "tmp"
-	}
-
+	runner := settings.Runner
 	scenarioCount := 1
 	outputRes := "4K"
-
-	stepPause := time.Second
-
-	subStepPause := 500 * time.Millisecond
 	temperature := 1.0 // 默认温度值
 
 	return RunOptions{
-		TargetURL:     "https://console.cloud.google.com/vertex-ai/studio/multimodal;mode=prompt?model=gemini-3-pro-image-preview",
-		ImagePath:     imagePath,
-		PromptText:    "",
-		DownloadDir:   downloadDir,
-		Headless:      true,
-		ScenarioCount: scenarioCount,
-		StepPause:     stepPause,
-		SubStepPause:  subStepPause,
-		OutputRes:     outputRes,
-		Temperature:   temperature,
+		Model:          settings.Models.Default,
+		PromptText:     "",
+		DownloadDir:    runner.DownloadDir,
+		Headless:       true,
+		ScenarioCount:  scenarioCount,
+		StepPause:      runner.StepPause,
+		SubStepPause:   runner.SubStepPause,
+		GotoTimeout:    runner.GotoTimeout,
+		TermsTimeout:   runner.TermsTimeout,
+		DownloadWait:   runner.DownloadWait,
+		OutputRes:      outputRes,
+		Temperature:    temperature,
+		TargetDeadline: runner.TargetDeadline,
 	}
 }
 
@@ -75,28 +99,48 @@ func RunWithOptions(ctx context.Context, opts RunOptions) ([]ScenarioResult, err
 		return nil, ctx.Err()
 	default:
 	}
+	model, err := ResolveModel(opts.Model)
+	if err != nil {
+		return nil, err
+	}
+	opts.Model = model
 	if opts.TargetURL == "" {
-		return nil, errors.New("TargetURL 不能为空")
+		opts.TargetURL = studioURL(model)
 	}
 	if opts.PromptText == "" {
 		return nil, errors.New("PromptText 不能为空")
 	}
-	// ImagePath现在可以为空，支持纯文本生成
+	// ImagePaths 可以为空，支持纯文本生成
+	if err := ValidateModelSettings(opts.AspectRatio, opts.CandidateCount); err != nil {
+		return nil, err
+	}
 	if opts.ScenarioCount < 1 {
 		opts.ScenarioCount = 1
 	}
 	if opts.DownloadDir == "" {
-		opts.DownloadDir = "tmp"
+		opts.DownloadDir = settings.Runner.DownloadDir
 	}
 	if opts.StepPause == 0 {
-		opts.StepPause = time.Second
+		opts.StepPause = settings.Runner.StepPause
 	}
 	if opts.SubStepPause == 0 {
-		opts.SubStepPause = 500 * time.Millisecond
+		opts.SubStepPause = settings.Runner.SubStepPause
+	}
+	if opts.GotoTimeout == 0 {
+		opts.GotoTimeout = settings.Runner.GotoTimeout
+	}
+	if opts.TermsTimeout == 0 {
+		opts.TermsTimeout = settings.Runner.TermsTimeout
+	}
+	if opts.DownloadWait == 0 {
+		opts.DownloadWait = settings.Runner.DownloadWait
 	}
 	if opts.OutputRes == "" {
 		opts.OutputRes = "4K"
 	}
+	if opts.TargetImages > 0 && opts.TargetDeadline <= 0 {
+		opts.TargetDeadline = settings.Runner.TargetDeadline
+	}
 
 	if err := os.MkdirAll(opts.DownloadDir, 0o755); err != nil {
 		return nil, fmt.Errorf("make download dir: %w", err)
@@ -105,76 +149,125 @@ func RunWithOptions(ctx context.Context, opts RunOptions) ([]ScenarioResult, err
 	proxyEndpoints := pickProxyEndpoints(ctx)
 
 	batchFolder := ""
-	if opts.ImagePath != "" {
-		batchFolder = sanitizeSegment(strings.TrimSuffix(filepath.Base(opts.ImagePath), filepath.Ext(opts.ImagePath)))
+	if len(opts.ImagePaths) > 0 {
+		first := opts.ImagePaths[0]
+		batchFolder = sanitizeSegment(strings.TrimSuffix(filepath.Base(first), filepath.Ext(first)))
 	} else {
 		batchFolder = fmt.Sprintf("text-only-%d", time.Now().Unix())
 	}
 
-	pw, err := playwright.Run()
-	if err != nil {
-		return nil, fmt.Errorf("start playwright: %w", err)
-	}
-	defer pw.Stop()
-
-	browserType := pw.Chromium
-	engineName := browserType.Name()
-	launchOpts := playwright.BrowserTypeLaunchOptions{
-		Headless: playwright.Bool(opts.Headless),
-		Args:     chromiumArgs,
-	}
-	browser, err := browserType.Launch(launchOpts)
-	if err != nil {
-		return nil, fmt.Errorf("launch browser: %w", err)
-	}
-	defer browser.Close()
-
+	engineName := browserPool.engine()
 	viewport := playwright.Size{Width: 1920, Height: 1080}
 	runCount := opts.ScenarioCount
 
 	assigned := proxyEndpoints
-	if len(assigned) > 0 && runCount > len(assigned) {
+	if opts.TargetImages == 0 && len(assigned) > 0 && runCount > len(assigned) {
 		fmt.Printf("⚠️ 并发数 %d 超过可用代理 %d，将限制为 %d\n", runCount, len(assigned), len(assigned))
 		runCount = len(assigned)
 	}
 
-	var wg sync.WaitGroup
-	errCh := make(chan error, runCount)
-	resultCh := make(chan ScenarioResult, runCount)
-	for i := 0; i < runCount; i++ {
-		var proxyURL, proxyTag string
-		if len(assigned) > 0 {
-			proxyURL = assigned[i].URL
-			proxyTag = assigned[i].Tag
-			fmt.Printf("🧭 [%d] Using proxy %s (tag=%s)\n", i+1, proxyURL, proxyTag)
+	// 固定模式：恰好启动 runCount 个场景。目标模式：缺多少成功就补多少场景，
+	// 有代理时直到节点耗尽，直连时最多尝试 directTargetAttempts 倍目标数。
+	maxAttempts := runCount
+	launchCtx := ctx
+	if opts.TargetImages > 0 {
+		maxAttempts = 0
+		if len(assigned) == 0 {
+			maxAttempts = opts.TargetImages * directTargetAttempts
 		}
-		wg.Add(1)
-		go func(id int, pURL, pTag string) {
-			defer wg.Done()
-			res, err := runScenario(ctx, browser, viewport, engineName, pURL, pTag, id, opts, batchFolder)
-			if err != nil {
-				res.Error = err.Error()
-				errCh <- fmt.Errorf("scenario %d: %w", id, err)
-			}
-			resultCh <- res
-		}(i+1, proxyURL, proxyTag)
+		var cancel context.CancelFunc
+		launchCtx, cancel = context.WithTimeout(ctx, opts.TargetDeadline)
+		defer cancel()
+		fmt.Printf("🎯 目标模式：需要 %d 张图片，截止 %s\n", opts.TargetImages, opts.TargetDeadline)
 	}
-	wg.Wait()
-	close(errCh)
-	close(resultCh)
 
+	type scenarioDone struct {
+		res ScenarioResult
+		err error
+	}
 	var (
-		results  []ScenarioResult
-		firstErr error
+		results   []ScenarioResult
+		firstErr  error
+		launched  int
+		inflight  int
+		successes int
+		stopped   bool
 	)
-	for r := range resultCh {
-		results = append(results, r)
+	doneCh := make(chan scenarioDone)
+	collect := func(d scenarioDone) {
+		inflight--
+		results = append(results, d.res)
+		if d.err != nil && firstErr == nil {
+			firstErr = d.err
+		}
+		// 页面不提供所选设置时换节点也无济于事
+		if errors.Is(d.err, steps.ErrOptionUnavailable) {
+			stopped = true
+		}
+		if d.res.Outcome == steps.DownloadOutcomeDownloaded {
+			successes += max(len(d.res.Paths), 1)
+		}
 	}
-	for e := range errCh {
-		if firstErr == nil {
-			firstErr = e
+	need := func() int {
+		if opts.TargetImages > 0 {
+			return opts.TargetImages - successes - inflight
 		}
+		return runCount - launched
 	}
+	used := map[string]bool{}
+	for {
+		if stopped || need() <= 0 || (maxAttempts > 0 && launched >= maxAttempts) {
+			if inflight == 0 {
+				break
+			}
+			collect(<-doneCh)
+			continue
+		}
+		id := launched + 1
+		sl, err := acquireSlot(launchCtx, opts, id, assigned, used)
+		if err != nil {
+			stopped = true
+			if ctx.Err() != nil && firstErr == nil {
+				firstErr = fmt.Errorf("scenario %d: %w", id, ctx.Err())
+			}
+			fmt.Printf("⚠️ [%d] 未能领取运行槽位，停止启动新场景：%v\n", id, err)
+			continue
+		}
+		// 排队期间可能已有场景完成，重新核算是否还需要这个场景
+	drain:
+		for {
+			select {
+			case d := <-doneCh:
+				collect(d)
+			default:
+				break drain
+			}
+		}
+		if need() <= 0 {
+			sl.release()
+			continue
+		}
+		launched++
+		inflight++
+		proxyURL, proxyTag := sl.Endpoint.URL, sl.Endpoint.Tag
+		if proxyTag != "" {
+			used[proxyTag] = true
+			fmt.Printf("🧭 [%d] Using proxy %s (tag=%s)\n", id, proxyURL, proxyTag)
+		}
+		go func(id int, pURL, pTag string, release func()) {
+			res, err := runScenario(ctx, viewport, engineName, pURL, pTag, id, opts, batchFolder)
+			release()
+			if err != nil {
+				res.Error = err.Error()
+				err = fmt.Errorf("scenario %d: %w", id, err)
+			}
+			doneCh <- scenarioDone{res: res, err: err}
+		}(id, proxyURL, proxyTag, sl.release)
+	}
+	if opts.TargetImages > 0 {
+		fmt.Printf("🎯 目标模式结束：成功 %d/%d，共启动 %d 个场景\n", successes, opts.TargetImages, launched)
+	}
+
 	anySuccess := false
 	for _, r := range results {
 		if r.Outcome == steps.DownloadOutcomeDownloaded {
@@ -188,6 +281,23 @@ func RunWithOptions(ctx context.Context, opts RunOptions) ([]ScenarioResult, err
 	return results, firstErr
 }
 
+// directTargetAttempts 直连（无代理池）时目标模式每张图片最多尝试的场景数。
+const directTargetAttempts = 3
+
+// acquireSlot 在全局调度器中排队，直到拿到浏览器上下文配额与代理节点。
+func acquireSlot(ctx context.Context, opts RunOptions, id int, pool []proxy.Endpoint, used map[string]bool) (slot, error) {
+	opts.emit(StepEvent{ScenarioID: id, Step: stepAcquireSlot, Phase: StepStarted})
+	started := time.Now()
+	sl, err := runScheduler.acquire(ctx, pool, used)
+	ev := StepEvent{ScenarioID: id, ProxyTag: sl.Endpoint.Tag, Step: stepAcquireSlot, Phase: StepSucceeded, DurationMs: time.Since(started).Milliseconds()}
+	if err != nil {
+		ev.Phase = StepFailed
+		ev.Error = err.Error()
+	}
+	opts.emit(ev)
+	return sl, err
+}
+
 func proxyOptions(url string) *playwright.Proxy {
 	if url == "" {
 		return nil
@@ -197,61 +307,80 @@ func proxyOptions(url string) *playwright.Proxy {
 	}
 }
 
+// proxySupervisor 是进程内唯一的 sing-box 实例，所有运行共享其节点列表。
+var proxySupervisor = proxy.NewSupervisor()
+
 func pickProxyEndpoints(ctx context.Context) []proxy.Endpoint {
-	if endpoints, stop, err := proxy.StartSingBox(ctx); err == nil && len(endpoints) > 0 {
-		fmt.Printf("🧭 使用 sing-box 代理，节点数：%d\n", len(endpoints))
-		if stop != nil {
-			go func() {
-				<-ctx.Done()
-				stop()
-			}()
-		}
+	// 服务器启动时已经 Start；CLI 等场景在首次运行时惰性启动
+	proxySupervisor.Start(context.Background())
+	if endpoints := proxySupervisor.Endpoints(ctx); len(endpoints) > 0 {
+		fmt.Printf("🧭 使用 sing-box 代理，可用节点数：%d\n", len(endpoints))
 		return endpoints
-	} else if err != nil {
-		fmt.Printf("⚠️ sing-box 启动失败，将直接直连：%v\n", err)
 	}
 	fmt.Println("🧭 未配置或未启用代理，直连运行")
 	return nil
 }
 
-func runScenario(ctx context.Context, browser playwright.Browser, viewport playwright.Size, engineName, proxyURL, proxyTag string, id int, opts RunOptions, batchFolder string) (ScenarioResult, error) {
-	res := ScenarioResult{ID: id, Outcome: steps.DownloadOutcomeNone, ProxyTag: proxyTag, OutputRes: opts.OutputRes}
+func runScenario(ctx context.Context, viewport playwright.Size, engineName, proxyURL, proxyTag string, id int, opts RunOptions, batchFolder string) (ScenarioResult, error) {
+	res := ScenarioResult{ID: id, Outcome: steps.DownloadOutcomeNone, ProxyTag: proxyTag, OutputRes: opts.OutputRes, Model: opts.Model}
 	if err := ctx.Err(); err != nil {
 		return res, err
 	}
 	penalized := false
-	freeze := func(reason string) {
+	generation := proxySupervisor.Generation()
+	// penalize 按结果类别冻结节点，每个场景只记录一次
+	penalize := func(outcome string) {
 		if penalized || res.ProxyTag == "" {
 			return
 		}
-		if err := proxy.FreezeEndpoint(res.ProxyTag); err != nil {
-			fmt.Printf("⚠️ [%d] 记录节点冻结失败(%s): %v\n", id, reason, err)
+		if outcome != proxy.OutcomeDownloaded && proxySupervisor.Generation() != generation {
+			// 订阅变更或崩溃导致 sing-box 重启，入站中断与节点无关
+			fmt.Printf("ℹ️ [%d] sing-box 在场景期间重启，不冻结节点 %s (%s)\n", id, res.ProxyTag, outcome)
+			penalized = true
+			return
+		}
+		if _, err := proxy.Penalize(res.ProxyTag, outcome); err != nil {
+			fmt.Printf("⚠️ [%d] 记录节点冻结失败(%s): %v\n", id, outcome, err)
 			return
 		}
 		penalized = true
 	}
+	var debug *debugRecorder
 	fail := func(reason string, err error) (ScenarioResult, error) {
 		if err == nil {
 			err = fmt.Errorf(reason)
 		}
-		freeze(reason)
+		if errors.Is(err, context.Canceled) {
+			// 用户取消与节点无关，不冻结也不计入失败
+			penalized = true
+		} else {
+			penalize(proxy.OutcomeError)
+		}
+		if debug != nil && !errors.Is(err, context.Canceled) {
+			if dir, derr := debug.save(artifactDir(opts, "debug", batchFolder, id), res, reason, err); derr != nil {
+				fmt.Printf("⚠️ [%d] 保存调试包失败: %v\n", id, derr)
+			} else {
+				res.DebugURL = "/" + filepath.ToSlash(dir) + "/"
+				fmt.Printf("🐞 [%d] Debug bundle saved: %s\n", id, dir)
+			}
+		}
 		return res, err
 	}
-	defer freeze("defer")
-
-	step := func(name string, pause time.Duration, fn func() (bool, error)) error {
-		ok, err := fn()
-		switch {
-		case err != nil:
-			fmt.Printf("⚠️ [%d] %s error: %v\n", id, name, err)
-			return fmt.Errorf("%s: %w", name, err)
-		case !ok:
-			fmt.Printf("⚠️ [%d] %s not completed\n", id, name)
-			return fmt.Errorf("%s not completed", name)
-		default:
-			fmt.Printf("✅ [%d] %s\n", id, name)
-			time.Sleep(pause)
-			return nil
+	defer penalize(string(steps.DownloadOutcomeNone))
+
+	// begin 发出步骤开始事件，返回用于上报结果（含耗时）的函数。
+	begin := func(name string) func(StepPhase, error) {
+		started := time.Now()
+		if debug != nil {
+			debug.step(name)
+		}
+		opts.emit(StepEvent{ScenarioID: id, ProxyTag: proxyTag, Step: name, Phase: StepStarted})
+		return func(phase StepPhase, err error) {
+			ev := StepEvent{ScenarioID: id, ProxyTag: proxyTag, Step: name, Phase: phase, DurationMs: time.Since(started).Milliseconds()}
+			if err != nil {
+				ev.Error = err.Error()
+			}
+			opts.emit(ev)
 		}
 	}
 
@@ -261,16 +390,36 @@ func runScenario(ctx context.Context, browser playwright.Browser, viewport playw
 	if proxyURL != "" {
 		ctxOpts.Proxy = proxyOptions(proxyURL)
 	}
-	browserCtx, err := browser.NewContext(ctxOpts)
+	browserCtx, err := browserPool.newContext(opts.Headless, ctxOpts)
 	if err != nil {
 		return fail("new context", fmt.Errorf("new context: %w", err))
 	}
 	defer browserCtx.Close()
 
+	if opts.Trace {
+		traceDir := artifactDir(opts, "traces", batchFolder, id)
+		if err := startTrace(browserCtx, traceDir, id); err != nil {
+			fmt.Printf("⚠️ [%d] 启动 trace 失败: %v\n", id, err)
+		} else {
+			tracePath := filepath.Join(traceDir, "trace.zip")
+			res.TraceURL = "/" + filepath.ToSlash(tracePath)
+			// 在 browserCtx.Close 之前执行
+			defer func() {
+				if err := browserCtx.Tracing().Stop(tracePath); err != nil {
+					fmt.Printf("⚠️ [%d] 保存 trace 失败: %v\n", id, err)
+					return
+				}
+				fmt.Printf("🧵 [%d] Trace saved: %s\n", id, tracePath)
+			}()
+		}
+	}
+
 	page, err := browserCtx.NewPage()
 	if err != nil {
 		return fail("new page", fmt.Errorf("new page: %w", err))
 	}
+	debug = newDebugRecorder(page)
+	capture := steps.StartImageCapture(page)
 
 	proxyInfo := proxyTag
 	if proxyInfo == "" && proxyURL != "" {
@@ -279,84 +428,49 @@ func runScenario(ctx context.Context, browser playwright.Browser, viewport playw
 	fmt.Printf("\n🚀 [%d] Starting (engine=%s headless=%v proxy=%s)\n", id, engineName, opts.Headless, proxyInfo)
 	fmt.Printf("🔎 [%d] Navigating to %s\n", id, opts.TargetURL)
 
-	_, err = page.Goto(opts.TargetURL, playwright.PageGotoOptions{
-		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
-		Timeout:   playwright.Float(15_000),
-	})
-	if err != nil {
-		fmt.Printf("⚠️ [%d] goto error: %v\n", id, err)
-		return fail("goto", err)
-	}
-	fmt.Printf("✅ [%d] URL after goto: %s\n", id, page.URL())
-	_ = page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{State: playwright.LoadStateDomcontentloaded})
-	_ = page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{State: playwright.LoadStateNetworkidle})
-
-	_ = page.BringToFront()
-	fmt.Printf("ℹ️ [%d] Brought page to front\n", id)
-	_ = page.Mouse().Click(5, 5)
-	_ = page.Keyboard().Press("Escape")
-	time.Sleep(opts.SubStepPause)
-
-	if err := step("Accept terms dialog", opts.StepPause, func() (bool, error) {
-		return steps.AcceptTermsBlocking(page, 45*time.Second)
-	}); err != nil {
-		return fail("accept terms", err)
-	}
-
-	if ok, err := steps.AcceptCookieBar(page); err != nil {
-		return fail("accept cookies bar", err)
-	} else if ok {
-		fmt.Printf("✅ [%d] Accept cookies bar\n", id)
-		time.Sleep(opts.StepPause)
-	} else {
-		fmt.Printf("ℹ️ [%d] Cookies bar not present, skipping\n", id)
-	}
-
-	if err := step("Open model settings", opts.StepPause, func() (bool, error) { return steps.OpenModelSettings(page) }); err != nil {
-		return fail("open model settings", err)
-	}
-
-	if err := step(fmt.Sprintf("Set output resolution to %s", opts.OutputRes), opts.StepPause, func() (bool, error) {
-		return steps.SetOutputResolution(page, opts.OutputRes)
-	}); err != nil {
-		return fail("set output resolution", err)
-	}
-
-	if opts.Temperature > 0 {
-		if err := step(fmt.Sprintf("Set temperature to %.1f", opts.Temperature), opts.StepPause, func() (bool, error) {
-			return steps.SetTemperature(page, opts.Temperature)
-		}); err != nil {
-			return fail("set temperature", err)
+	stepEvent := func(name string, phase StepPhase, attempts int, elapsed time.Duration, err error) {
+		ev := StepEvent{ScenarioID: id, ProxyTag: proxyTag, Step: name, Phase: phase, Attempts: attempts, DurationMs: elapsed.Milliseconds()}
+		if err != nil {
+			ev.Error = err.Error()
 		}
-	} else {
-		fmt.Printf("ℹ️ [%d] Skipping temperature setting (not provided)\n", id)
-	}
-
-	if err := step("Enter prompt text", opts.StepPause, func() (bool, error) {
-		return steps.EnterPrompt(page, opts.PromptText)
-	}); err != nil {
-		return fail("prompt input failed", err)
-	}
-	length := promptLength(page)
-	fmt.Printf("ℹ️ [%d] Prompt length after entry: %d chars\n", id, length)
-	if length == 0 {
-		return fail("prompt is empty after entry", fmt.Errorf("prompt is empty after entry"))
-	}
-
-	// 只有当ImagePath不为空时才上传图片
-	if opts.ImagePath != "" {
-		if err := step("Upload local image", opts.StepPause, func() (bool, error) {
-			return steps.UploadLocalFile(page, opts.ImagePath)
-		}); err != nil {
-			return fail("upload failed", err)
+		opts.emit(ev)
+	}
+	pipeline := steps.Pipeline{
+		Steps: scenarioSteps(id, opts),
+		Pause: opts.StepPause,
+		Hooks: steps.Hooks{
+			OnStart: func(name string) {
+				if name == stepSubmit {
+					capture.Arm()
+				}
+				debug.step(name)
+				stepEvent(name, StepStarted, 0, 0, nil)
+			},
+			OnSkip: func(name string) {
+				fmt.Printf("ℹ️ [%d] %s skipped\n", id, name)
+				stepEvent(name, StepSkipped, 0, 0, nil)
+			},
+			OnRetry: func(name string, attempt int, err error) {
+				fmt.Printf("🔁 [%d] %s retry #%d: %v\n", id, name, attempt, err)
+				stepEvent(name, StepRetrying, attempt, 0, err)
+			},
+			OnSuccess: func(name string, attempts int, elapsed time.Duration) {
+				fmt.Printf("✅ [%d] %s\n", id, name)
+				stepEvent(name, StepSucceeded, attempts, elapsed, nil)
+			},
+			OnFailure: func(name string, attempts int, elapsed time.Duration, err error) {
+				fmt.Printf("⚠️ [%d] %v\n", id, err)
+				stepEvent(name, StepFailed, attempts, elapsed, err)
+			},
+		},
+	}
+	if err := pipeline.Run(ctx, page); err != nil {
+		var stepErr *steps.StepError
+		if errors.As(err, &stepErr) {
+			res.FailedStep = stepErr.Step
+			res.Attempts = stepErr.Attempts
 		}
-	} else {
-		fmt.Printf("ℹ️ [%d] No image provided, skipping upload\n", id)
-		time.Sleep(opts.StepPause)
-	}
-
-	if err := step("Submit prompt", opts.StepPause, func() (bool, error) { return steps.SubmitPrompt(page) }); err != nil {
-		return fail("submit prompt failed", err)
+		return fail("pipeline", err)
 	}
 
 	if err := ctx.Err(); err != nil {
@@ -364,32 +478,92 @@ func runScenario(ctx context.Context, browser playwright.Browser, viewport playw
 	}
 
 	outDir := filepath.Join(opts.DownloadDir, batchFolder)
-	outcome, path, err := steps.DownloadImage(ctx, page, outDir, 720*time.Second)
+	endDownload := begin("Download image")
+	outcome, paths, err := steps.DownloadImages(ctx, page, capture, outDir, opts.DownloadWait)
 	res.Outcome = outcome
-	res.Path = path
-	if path != "" {
-		res.URL = "/" + filepath.ToSlash(path)
+	res.Text, res.BlockReason = capture.Text(), capture.BlockReason()
+	for _, p := range paths {
+		res.Paths = append(res.Paths, p)
+		res.URLs = append(res.URLs, "/"+filepath.ToSlash(p))
+	}
+	if len(paths) > 0 {
+		res.Path, res.URL = res.Paths[0], res.URLs[0]
 	}
 	if err != nil {
+		endDownload(StepFailed, err)
 		return fail("download", fmt.Errorf("download: %w", err))
 	}
-	switch outcome {
-	case steps.DownloadOutcomeDownloaded:
-		fmt.Printf("✅ [%d] Downloaded image\n", id)
-		freeze("downloaded")
-	case steps.DownloadOutcomeExhausted:
-		fmt.Printf("⚠️ [%d] Resource exhausted (429/quota)\n", id)
-		freeze("exhausted")
+	if outcome == steps.DownloadOutcomeDownloaded {
+		endDownload(StepSucceeded, nil)
+	} else {
+		endDownload(StepFailed, fmt.Errorf("download outcome: %s", outcome))
+	}
+	switch {
+	case outcome == steps.DownloadOutcomeDownloaded:
+		fmt.Printf("✅ [%d] Downloaded %d image(s)\n", id, len(res.Paths))
+	case outcome.Failed():
+		fmt.Printf("⚠️ [%d] Generation failed: %s\n", id, outcome)
+		res.Error = outcomeMessages[outcome]
+		if res.BlockReason != "" {
+			res.Error += ": " + res.BlockReason
+		}
 	default:
 		fmt.Printf("ℹ️ [%d] Download not completed\n", id)
 	}
+	penalize(string(outcome))
 
 	fmt.Printf("🛑 [%d] Flow done, closing context\n", id)
 	return res, nil
 }
 
+// startTrace 创建 trace 目录并开始录制截图、DOM 快照与源码。
+func startTrace(browserCtx playwright.BrowserContext, dir string, id int) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("make trace dir: %w", err)
+	}
+	return browserCtx.Tracing().Start(playwright.TracingStartOptions{
+		Title:       playwright.String(fmt.Sprintf("scenario %d", id)),
+		Screenshots: playwright.Bool(true),
+		Snapshots:   playwright.Bool(true),
+		Sources:     playwright.Bool(true),
+	})
+}
+
+// ResolveModel 返回要使用的模型 ID：空值取配置的默认模型，不在支持列表中时报错。
+func ResolveModel(model string) (string, error) {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return settings.Models.Default, nil
+	}
+	if !slices.Contains(settings.Models.Supported, model) {
+		return "", fmt.Errorf("model %q 不受支持，可选: %s", model, strings.Join(settings.Models.Supported, ", "))
+	}
+	return model, nil
+}
+
+// studioURL 返回模型对应的 Vertex Studio 页面地址。
+func studioURL(model string) string {
+	return settings.Models.StudioURL + "?model=" + url.QueryEscape(model)
+}
+
+// maxCandidateCount 是 Studio 单次提示词允许的最大候选数。
+const maxCandidateCount = 8
+
+var aspectRatioPattern = regexp.MustCompile(`^\d{1,2}:\d{1,2}$`)
+
+// ValidateModelSettings 检查宽高比与候选数的格式；页面是否提供该值在运行时由对应步骤判断。
+func ValidateModelSettings(aspectRatio string, candidateCount int) error {
+	if aspectRatio != "" && !aspectRatioPattern.MatchString(aspectRatio) {
+		return fmt.Errorf("aspectRatio %q 格式无效，应形如 16:9", aspectRatio)
+	}
+	if candidateCount < 0 || candidateCount > maxCandidateCount {
+		return fmt.Errorf("candidateCount 需在 1 到 %d 之间（0 表示使用页面默认值），当前为 %d", maxCandidateCount, candidateCount)
+	}
+	return nil
+}
+
 func promptLength(page playwright.Page) int {
-	loc := page.Locator("ai-llm-prompt-input-box textarea, ai-llm-prompt-input-box [role=\"textbox\"], ai-llm-prompt-input-box [contenteditable=\"true\"]").First()
+	loc := steps.PromptInput(page)
 	val, _ := loc.InputValue()
 	if val == "" {
 		val, _ = loc.InnerText()
@@ -411,16 +585,3 @@ func sanitizeSegment(name string) string {
 	}
 	return name
 }
-
-var (
-	chromiumArgs = []string{
-		"--start-maximized",
-		"--window-size=1920,1080",
-		"--autoplay-policy=no-user-gesture-required",
-		"--disable-features=IsolateOrigins,site-per-process,AutomationControlled",
-		"--disable-blink-features=AutomationControlled",
-		"--no-sandbox",
-		"--disable-dev-shm-usage",
-		"--incognito",
-	}
-)
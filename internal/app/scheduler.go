@@ -0,0 +1,129 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"vertex-nano-banana-unlimited/internal/proxy"
+)
+
+// stepAcquireSlot 场景排队领取浏览器上下文与代理节点的步骤名。
+const stepAcquireSlot = "Acquire browser slot"
+
+var errPoolDrained = errors.New("没有可用的代理节点")
+
+var runScheduler = newScheduler(settings.Runner.MaxContexts)
+
+// scheduler 在所有并发运行之间共享浏览器上下文预算和代理节点。
+// 场景按到达顺序排队领取 slot；同一节点同一时间只分配给一个场景。
+type scheduler struct {
+	mu          sync.Mutex
+	maxContexts int
+	inUse       int
+	leased      map[string]bool
+	seq         uint64
+	queue       []uint64
+	wake        chan struct{}
+}
+
+// slot 是一个场景占用的浏览器上下文配额以及（可选的）代理节点。
+type slot struct {
+	Endpoint proxy.Endpoint
+	release  func()
+}
+
+func newScheduler(maxContexts int) *scheduler {
+	if maxContexts < 1 {
+		maxContexts = 1
+	}
+	return &scheduler{
+		maxContexts: maxContexts,
+		leased:      map[string]bool{},
+		wake:        make(chan struct{}),
+	}
+}
+
+// acquire 阻塞直到轮到调用者且有空闲的上下文配额与节点。pool 为空表示直连；
+// skip 中的节点（本次运行已用过）及冻结节点不会被分配，全部不可用时返回 errPoolDrained。
+func (s *scheduler) acquire(ctx context.Context, pool []proxy.Endpoint, skip map[string]bool) (slot, error) {
+	s.mu.Lock()
+	s.seq++
+	me := s.seq
+	s.queue = append(s.queue, me)
+	for {
+		if s.queue[0] == me {
+			ep, ok, drained := s.tryLocked(pool, skip)
+			if ok || drained {
+				s.dequeueLocked(me)
+				if drained {
+					s.mu.Unlock()
+					return slot{}, errPoolDrained
+				}
+				s.inUse++
+				if ep.Tag != "" {
+					s.leased[ep.Tag] = true
+				}
+				s.mu.Unlock()
+				return slot{Endpoint: ep, release: s.releaseFunc(ep.Tag)}, nil
+			}
+		}
+		wake := s.wake
+		s.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.dequeueLocked(me)
+			s.mu.Unlock()
+			return slot{}, ctx.Err()
+		case <-wake:
+		}
+		s.mu.Lock()
+	}
+}
+
+func (s *scheduler) tryLocked(pool []proxy.Endpoint, skip map[string]bool) (proxy.Endpoint, bool, bool) {
+	if len(pool) == 0 {
+		return proxy.Endpoint{}, s.inUse < s.maxContexts, false
+	}
+	frozen := proxy.FrozenSet()
+	candidates := 0
+	for _, ep := range pool {
+		if skip[ep.Tag] || frozen[ep.Tag] {
+			continue
+		}
+		candidates++
+		if !s.leased[ep.Tag] && s.inUse < s.maxContexts {
+			return ep, true, false
+		}
+	}
+	return proxy.Endpoint{}, false, candidates == 0
+}
+
+func (s *scheduler) releaseFunc(tag string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.inUse--
+			delete(s.leased, tag)
+			s.broadcastLocked()
+			s.mu.Unlock()
+		})
+	}
+}
+
+func (s *scheduler) dequeueLocked(me uint64) {
+	for i, w := range s.queue {
+		if w == me {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			break
+		}
+	}
+	s.broadcastLocked()
+}
+
+func (s *scheduler) broadcastLocked() {
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
@@ -0,0 +1,213 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	playwright "github.com/playwright-community/playwright-go"
+
+	"vertex-nano-banana-unlimited/internal/selectors"
+	"vertex-nano-banana-unlimited/internal/steps"
+)
+
+// selectorWatchInterval 是检查定位器覆盖文件是否变化的间隔。
+const selectorWatchInterval = 5 * time.Second
+
+// selectorTestTimeout 限制一次 /selectors/test 的总耗时（含排队）。
+const selectorTestTimeout = 3 * time.Minute
+
+type selectorTestRequest struct {
+	Model        string          `json:"model"`
+	URL          string          `json:"url"`
+	Names        []string        `json:"names"`
+	Selectors    json.RawMessage `json:"selectors"`    // 临时定位器定义（YAML 字符串或 JSON 对象），不影响注册表
+	OpenSettings bool            `json:"openSettings"` // 先展开模型设置面板再检测
+	Headless     *bool           `json:"headless"`
+}
+
+// selectorStrategyResult 是单个策略在页面上的匹配情况。
+type selectorStrategyResult struct {
+	Strategy selectors.Strategy `json:"strategy"`
+	Count    int                `json:"count"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// selectorSetResult 是一组策略（Or 组合后）的匹配情况。
+type selectorSetResult struct {
+	Name       string                   `json:"name"`
+	Count      int                      `json:"count"`
+	Visible    bool                     `json:"visible"`
+	Strategies []selectorStrategyResult `json:"strategies"`
+}
+
+func handleListSelectors(w http.ResponseWriter, r *http.Request) {
+	sets, loadedAt := selectorRegistry.Snapshot()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"source":   selectorRegistry.Path(),
+		"loadedAt": loadedAt,
+		"sets":     sets,
+	})
+}
+
+func handleReloadSelectors(w http.ResponseWriter, r *http.Request) {
+	if err := selectorRegistry.Reload(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	_, loadedAt := selectorRegistry.Snapshot()
+	fmt.Printf("🔄 定位器已重新加载: %s\n", selectorRegistry.Path())
+	writeJSON(w, http.StatusOK, map[string]any{"status": "reloaded", "loadedAt": loadedAt})
+}
+
+// handleTestSelectors 打开 Studio 页面（可选展开模型设置），逐个统计定位器的匹配数量，
+// 用于 UI 改版后验证覆盖文件。占用一个调度 slot，但不会冻结节点。
+// 嵌套条目（如 terms.checkbox）在整个页面范围内统计。
+func handleTestSelectors(w http.ResponseWriter, r *http.Request) {
+	var req selectorTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
+		return
+	}
+
+	sets, _ := selectorRegistry.Snapshot()
+	if len(req.Selectors) > 0 {
+		adhoc, err := parseAdhocSelectors(req.Selectors)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		sets = adhoc
+	}
+	names := req.Names
+	if len(names) == 0 {
+		names = sets.Names()
+	}
+	for _, name := range names {
+		if _, ok := sets[name]; !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unknown selector %q", name)})
+			return
+		}
+	}
+
+	opts := DefaultRunOptions()
+	if req.Headless != nil {
+		opts.Headless = *req.Headless
+	}
+	if req.URL != "" {
+		// 服务未鉴权且允许跨域，只允许打开配置的 Studio 站点
+		if err := checkStudioURL(req.URL); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		opts.TargetURL = req.URL
+	} else {
+		model, err := ResolveModel(req.Model)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		opts.Model = model
+		opts.TargetURL = studioURL(model)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), selectorTestTimeout)
+	defer cancel()
+	results, pageURL, err := testSelectors(ctx, opts, sets, names, req.OpenSettings)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"url": pageURL, "results": results})
+}
+
+// checkStudioURL 要求 raw 是与 models.studioURL 同协议、同主机的地址。
+func checkStudioURL(raw string) error {
+	studio, err := url.Parse(settings.Models.StudioURL)
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != studio.Scheme || !strings.EqualFold(u.Host, studio.Host) {
+		return fmt.Errorf("url must be on %s://%s", studio.Scheme, studio.Host)
+	}
+	return nil
+}
+
+// parseAdhocSelectors 接受 YAML 字符串或 JSON 对象形式的定位器定义。
+func parseAdhocSelectors(raw json.RawMessage) (selectors.Sets, error) {
+	data := []byte(raw)
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		data = []byte(text)
+	}
+	sets, err := selectors.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selectors:\n%w", err)
+	}
+	if len(sets) == 0 {
+		return nil, errors.New("selectors is empty")
+	}
+	return sets, nil
+}
+
+func testSelectors(ctx context.Context, opts RunOptions, sets selectors.Sets, names []string, openSettings bool) ([]selectorSetResult, string, error) {
+	sl, err := runScheduler.acquire(ctx, pickProxyEndpoints(ctx), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer sl.release()
+
+	ctxOpts := playwright.BrowserNewContextOptions{
+		Viewport: &playwright.Size{Width: 1920, Height: 1080},
+		Proxy:    proxyOptions(sl.Endpoint.URL),
+	}
+	browserCtx, err := browserPool.newContext(opts.Headless, ctxOpts)
+	if err != nil {
+		return nil, "", fmt.Errorf("new context: %w", err)
+	}
+	defer browserCtx.Close()
+	page, err := browserCtx.NewPage()
+	if err != nil {
+		return nil, "", fmt.Errorf("new page: %w", err)
+	}
+
+	// 导航、条款、cookies，以及（可选）展开模型设置
+	stepNames := []string{stepNavigate, stepAcceptTerms, stepAcceptCookies}
+	if openSettings {
+		stepNames = append(stepNames, stepOpenSettings)
+	}
+	prepare, err := pickSteps(scenarioSteps(0, opts), stepNames...)
+	if err != nil {
+		return nil, "", err
+	}
+	pipeline := steps.Pipeline{Steps: prepare, Pause: opts.StepPause}
+	if err := pipeline.Run(ctx, page); err != nil {
+		return nil, page.URL(), err
+	}
+
+	root := page.Locator(":root")
+	results := make([]selectorSetResult, 0, len(names))
+	for _, name := range names {
+		set := sets[name]
+		res := selectorSetResult{Name: name}
+		combined := set.Locate(root)
+		res.Count, _ = combined.Count()
+		res.Visible, _ = combined.First().IsVisible()
+		for _, st := range set {
+			sr := selectorStrategyResult{Strategy: st}
+			if sr.Count, err = st.Locate(root).Count(); err != nil {
+				sr.Error = err.Error()
+			}
+			res.Strategies = append(res.Strategies, sr)
+		}
+		results = append(results, res)
+	}
+	return results, page.URL(), nil
+}
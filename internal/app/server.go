@@ -11,22 +11,16 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"vertex-nano-banana-unlimited/internal/imageprocessing"
 	"vertex-nano-banana-unlimited/internal/proxy"
 )
 
-var (
-	activeRunCancel   context.CancelFunc
-	activeRunToken    int64
-	activeRunCancelMu sync.Mutex
-)
-
 const maxUploadBytes int64 = 7 * 1024 * 1024
 
 // corsMiddleware 添加CORS头部，允许所有来源
@@ -54,6 +48,10 @@ func corsMiddlewareForFunc(handler func(http.ResponseWriter, *http.Request)) htt
 }
 
 func StartHTTPServer(ctx context.Context, addr string) error {
+	proxySupervisor.Start(ctx)
+	ResumeBatches(ctx)
+	go selectorRegistry.Watch(ctx, selectorWatchInterval)
+
 	mux := http.NewServeMux()
 	mux.Handle("/", corsMiddleware(http.FileServer(http.Dir("."))))
 	mux.Handle("/healthz", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -64,10 +62,27 @@ func StartHTTPServer(ctx context.Context, addr string) error {
 			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only POST allowed"})
 			return
 		}
-		if cancelled := cancelActiveRun(); cancelled {
-			writeJSON(w, http.StatusOK, map[string]string{"status": "cancelled"})
-		} else {
-			writeJSON(w, http.StatusOK, map[string]string{"status": "idle"})
+		// 只取消指定的任务，避免影响其他人的运行
+		id := strings.TrimSpace(r.URL.Query().Get("jobId"))
+		if id == "" {
+			var body struct {
+				JobID string `json:"jobId"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			id = strings.TrimSpace(body.JobID)
+		}
+		if id == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "jobId is required"})
+			return
+		}
+		found, cancelled := jobs.cancel(id)
+		switch {
+		case !found:
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("job %s 不存在", id)})
+		case cancelled:
+			writeJSON(w, http.StatusOK, map[string]string{"status": "cancelled", "jobId": id})
+		default:
+			writeJSON(w, http.StatusOK, map[string]string{"status": "idle", "jobId": id})
 		}
 	}))
 	mux.Handle("/run", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -82,6 +97,89 @@ func StartHTTPServer(ctx context.Context, addr string) error {
 			handleJSONRun(w, r)
 		}
 	}))
+	mux.Handle("/models", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET allowed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"default":   settings.Models.Default,
+			"supported": settings.Models.Supported,
+		})
+	}))
+	mux.Handle("/selectors", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET allowed"})
+			return
+		}
+		handleListSelectors(w, r)
+	}))
+	mux.Handle("/selectors/reload", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only POST allowed"})
+			return
+		}
+		handleReloadSelectors(w, r)
+	}))
+	mux.Handle("/selectors/test", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only POST allowed"})
+			return
+		}
+		handleTestSelectors(w, r)
+	}))
+	mux.Handle("/jobs", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET allowed"})
+			return
+		}
+		handleListJobs(w, r)
+	}))
+	mux.Handle("/jobs/{id}", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET allowed"})
+			return
+		}
+		handleGetJob(w, r)
+	}))
+	mux.Handle("/jobs/{id}/events", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET allowed"})
+			return
+		}
+		handleJobEvents(w, r)
+	}))
+	mux.Handle("/batches", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleListBatches(w, r)
+		case http.MethodPost:
+			handleCreateBatch(w, r)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET/POST allowed"})
+		}
+	}))
+	mux.Handle("/batches/{id}", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET allowed"})
+			return
+		}
+		handleGetBatch(w, r)
+	}))
+	mux.Handle("/batches/{id}/cancel", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only POST allowed"})
+			return
+		}
+		handleCancelBatch(w, r)
+	}))
+	mux.Handle("/history", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET allowed"})
+			return
+		}
+		handleHistory(w, r)
+	}))
 	mux.Handle("/gallery", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET allowed"})
@@ -97,6 +195,27 @@ func StartHTTPServer(ctx context.Context, addr string) error {
 		handleGalleryFiles(w, r)
 	}))
 	mux.Handle("/proxy/subscriptions", corsMiddlewareForFunc(handleProxySubscriptions))
+	mux.Handle("/proxy/nodes", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET allowed"})
+			return
+		}
+		handleListProxyNodes(w, r)
+	}))
+	mux.Handle("/proxy/nodes/{tag}/freeze", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only POST allowed"})
+			return
+		}
+		handleFreezeProxyNode(w, r)
+	}))
+	mux.Handle("/proxy/nodes/{tag}/unfreeze", corsMiddlewareForFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only POST allowed"})
+			return
+		}
+		handleUnfreezeProxyNode(w, r)
+	}))
 
 	srv := &http.Server{
 		Addr:    addr,
@@ -106,6 +225,7 @@ func StartHTTPServer(ctx context.Context, addr string) error {
 	go func() {
 		<-ctx.Done()
 		_ = srv.Shutdown(context.Background())
+		Shutdown()
 	}()
 
 	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -114,36 +234,46 @@ func StartHTTPServer(ctx context.Context, addr string) error {
 	return nil
 }
 
-func cancelActiveRun() bool {
-	activeRunCancelMu.Lock()
-	defer activeRunCancelMu.Unlock()
-	if activeRunCancel != nil {
-		activeRunCancel()
-		activeRunCancel = nil
-		return true
-	}
-	return false
+// Shutdown 释放进程级共享资源（浏览器池、sing-box 等）。
+func Shutdown() {
+	browserPool.close()
+	proxySupervisor.Stop()
 }
 
-func runWithExclusive(ctx context.Context, opts RunOptions) ([]ScenarioResult, error) {
-	activeRunCancelMu.Lock()
-	if activeRunCancel != nil {
-		activeRunCancel()
-	}
-	activeRunToken++
-	token := activeRunToken
-	cctx, cancel := context.WithCancel(ctx)
-	activeRunCancel = cancel
-	activeRunCancelMu.Unlock()
+// PrepareImages 将输入图片逐一转换为可上传的 PNG（不超过 7MB），无需处理的图片原样返回路径。
+func PrepareImages(srcPaths []string) ([]string, error) {
+	return prepareImagesForRun(srcPaths)
+}
 
-	results, err := RunWithOptions(cctx, opts)
+func prepareImagesForRun(srcPaths []string) ([]string, error) {
+	var out []string
+	for _, src := range srcPaths {
+		processed, err := prepareImageForRun(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(src), err)
+		}
+		out = append(out, processed)
+	}
+	return out, nil
+}
 
-	activeRunCancelMu.Lock()
-	if activeRunToken == token {
-		activeRunCancel = nil
+// saveUploadedFile 把上传的文件写入临时文件并返回路径。
+func saveUploadedFile(header *multipart.FileHeader) (string, error) {
+	src, err := header.Open()
+	if err != nil {
+		return "", err
 	}
-	activeRunCancelMu.Unlock()
-	return results, err
+	defer src.Close()
+	tmp, err := os.CreateTemp("", "upload-*"+filepath.Ext(header.Filename))
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+	if _, err := io.Copy(tmp, src); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
 }
 
 func prepareImageForRun(srcPath string) (string, error) {
@@ -191,54 +321,77 @@ func shouldProcessImage(info fs.FileInfo, ext string) bool {
 }
 
 func handleJSONRun(w http.ResponseWriter, r *http.Request) {
-	cancelActiveRun()
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("read body: %v", err)})
 		return
 	}
 	var req struct {
-		Image         string  `json:"image"`
-		Prompt        string  `json:"prompt"`
-		ScenarioCount int     `json:"scenarioCount"`
-		Resolution    string  `json:"resolution"`
-		Temperature   float64 `json:"temperature"`
+		Image           string   `json:"image"`
+		Images          []string `json:"images"`
+		Prompt          string   `json:"prompt"`
+		Model           string   `json:"model"`
+		ScenarioCount   int      `json:"scenarioCount"`
+		Resolution      string   `json:"resolution"`
+		Temperature     float64  `json:"temperature"`
+		AspectRatio     string   `json:"aspectRatio"`
+		CandidateCount  int      `json:"candidateCount"`
+		TargetImages    int      `json:"targetImages"`
+		DeadlineSeconds int      `json:"deadlineSeconds"`
+		Trace           bool     `json:"trace"`
 	}
 	if err := json.Unmarshal(body, &req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid json: %v", err)})
 		return
 	}
 	req.Prompt = strings.TrimSpace(req.Prompt)
-	req.Image = strings.TrimSpace(req.Image)
 	req.Resolution = strings.TrimSpace(req.Resolution)
+	req.AspectRatio = strings.TrimSpace(req.AspectRatio)
 	if req.Prompt == "" {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "prompt 不能为空"})
 		return
 	}
-	// 只有当image不为空时才检查文件存在性
-	if req.Image != "" {
-		if _, err := os.Stat(req.Image); err != nil {
+	if err := ValidateModelSettings(req.AspectRatio, req.CandidateCount); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	model, err := ResolveModel(req.Model)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	// image 与 images 可同时使用，image 排在最前；都为空时纯文本生成
+	var images []string
+	for _, img := range append([]string{req.Image}, req.Images...) {
+		if img = strings.TrimSpace(img); img == "" {
+			continue
+		}
+		if _, err := os.Stat(img); err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("image 不可用: %v", err)})
 			return
 		}
+		images = append(images, img)
 	}
 
 	opts := DefaultRunOptions()
-	var processedPath string
-
-	// 只有当image不为空时才处理图片
-	if req.Image != "" {
-		var err error
-		processedPath, err = prepareImageForRun(req.Image)
-		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("处理图片失败: %v", err)})
-			return
+	processed, err := prepareImagesForRun(images)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("处理图片失败: %v", err)})
+		return
+	}
+	// 处理后生成的临时图片在任务结束后删除，调用方提供的原图保留
+	var tmpNames []string
+	for _, p := range processed {
+		if !slices.Contains(images, p) {
+			tmpNames = append(tmpNames, p)
 		}
-		opts.ImagePath = processedPath
-	} else {
-		// image为空时，ImagePath保持为空字符串
-		opts.ImagePath = ""
 	}
+	cleanup := func() {
+		for _, name := range tmpNames {
+			_ = os.Remove(name)
+		}
+	}
+	opts.ImagePaths = processed
 	opts.PromptText = req.Prompt
 	if req.Resolution != "" {
 		opts.OutputRes = req.Resolution
@@ -253,35 +406,28 @@ func handleJSONRun(w http.ResponseWriter, r *http.Request) {
 	if req.Temperature > 0 {
 		opts.Temperature = req.Temperature
 	}
-
-	fmt.Printf("▶️ /run (json) image=%s processed=%s scenario=%d res=%s temp=%.1f promptLen=%d\n", req.Image, processedPath, opts.ScenarioCount, opts.OutputRes, opts.Temperature, len(opts.PromptText))
-	results, runErr := runWithExclusive(r.Context(), opts)
-	if runErr != nil {
-		status := http.StatusInternalServerError
-		msg := runErr.Error()
-		if errors.Is(runErr, context.Canceled) {
-			status = http.StatusConflict
-			msg = "cancelled"
-		}
-		fmt.Printf("⚠️ /run (json) end err=%v\n", runErr)
-		writeJSON(w, status, map[string]any{
-			"error":   msg,
-			"results": results,
-		})
-		return
+	if req.TargetImages > 0 {
+		opts.TargetImages = req.TargetImages
 	}
-	fmt.Printf("✅ /run (json) done scenario=%d res=%s results=%d\n", opts.ScenarioCount, opts.OutputRes, len(results))
-	writeJSON(w, http.StatusOK, map[string]any{
-		"status":        "ok",
-		"imageUsed":     processedPath,
-		"imageOrig":     req.Image,
-		"scenarioCount": opts.ScenarioCount,
-		"results":       results,
+	if req.DeadlineSeconds > 0 {
+		opts.TargetDeadline = time.Duration(req.DeadlineSeconds) * time.Second
+	}
+	opts.Trace = req.Trace
+	opts.AspectRatio = req.AspectRatio
+	opts.Model = model
+	opts.CandidateCount = req.CandidateCount
+
+	fmt.Printf("▶️ /run (json) images=%v processed=%v scenario=%d target=%d res=%s temp=%.1f promptLen=%d trace=%v\n", images, processed, opts.ScenarioCount, opts.TargetImages, opts.OutputRes, opts.Temperature, len(opts.PromptText), opts.Trace)
+	job := jobs.submit(opts, images, cleanup)
+	fmt.Printf("📥 /run (json) queued job=%s\n", job.ID)
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"status": job.Status,
+		"jobId":  job.ID,
+		"job":    job,
 	})
 }
 
 func handleMultipartRun(w http.ResponseWriter, r *http.Request) {
-	cancelActiveRun()
 	if err := r.ParseMultipartForm(32 << 20); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("parse form: %v", err)})
 		return
@@ -300,33 +446,63 @@ func handleMultipartRun(w http.ResponseWriter, r *http.Request) {
 			temperature = t
 		}
 	}
-	var tmpFile *os.File
-	var header *multipart.FileHeader
-	var processedPath string
-
-	file, header, err := r.FormFile("image")
-	if err != nil {
-		if err != http.ErrMissingFile {
-			// 真正的错误，不是文件缺失
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("读取 image 文件字段失败: %v", err)})
-			return
+	targetImages := 0
+	if v := strings.TrimSpace(r.FormValue("targetImages")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			targetImages = n
 		}
-		// 没有上传文件是允许的
-		processedPath = ""
-	} else {
-		defer file.Close()
-
-		tmpFile, err = os.CreateTemp("", "upload-*"+filepath.Ext(header.Filename))
+	}
+	var targetDeadline time.Duration
+	if v := strings.TrimSpace(r.FormValue("deadlineSeconds")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			targetDeadline = time.Duration(n) * time.Second
+		}
+	}
+	trace, _ := strconv.ParseBool(strings.TrimSpace(r.FormValue("trace")))
+	aspectRatio := strings.TrimSpace(r.FormValue("aspectRatio"))
+	candidateCount := 0
+	if v := strings.TrimSpace(r.FormValue("candidateCount")); v != "" {
+		n, err := strconv.Atoi(v)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("create temp: %v", err)})
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("candidateCount 无效: %s", v)})
 			return
 		}
-		defer os.Remove(tmpFile.Name())
-		if _, err := io.Copy(tmpFile, file); err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("save temp: %v", err)})
-			return
+		candidateCount = n
+	}
+	if err := ValidateModelSettings(aspectRatio, candidateCount); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	model, err := ResolveModel(r.FormValue("model"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	// 临时文件需要保留到后台任务结束；提交任务后由任务负责清理。
+	var tmpNames []string
+	cleanup := func() {
+		for _, name := range tmpNames {
+			_ = os.Remove(name)
+		}
+	}
+	defer func() {
+		if cleanup != nil {
+			cleanup()
+		}
+	}()
+
+	// image 可重复出现，也接受 images 字段；没有上传文件是允许的
+	var filenames []string
+	for _, field := range []string{"image", "images"} {
+		for _, header := range r.MultipartForm.File[field] {
+			name, err := saveUploadedFile(header)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("save temp: %v", err)})
+				return
+			}
+			tmpNames = append(tmpNames, name)
+			filenames = append(filenames, header.Filename)
 		}
-		processedPath = tmpFile.Name()
 	}
 
 	if prompt == "" {
@@ -335,20 +511,17 @@ func handleMultipartRun(w http.ResponseWriter, r *http.Request) {
 	}
 
 	opts := DefaultRunOptions()
-	var finalProcessPath string
-
-	// 只有当有上传文件时才处理图片
-	if processedPath != "" {
-		var err error
-		finalProcessPath, err = prepareImageForRun(processedPath)
-		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("处理图片失败: %v", err)})
-			return
+	processed, err := prepareImagesForRun(tmpNames)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("处理图片失败: %v", err)})
+		return
+	}
+	for _, p := range processed {
+		if !slices.Contains(tmpNames, p) {
+			tmpNames = append(tmpNames, p)
 		}
-		opts.ImagePath = finalProcessPath
-	} else {
-		opts.ImagePath = ""
 	}
+	opts.ImagePaths = processed
 	opts.PromptText = prompt
 	opts.ScenarioCount = scenarioCount
 	if resolution != "" {
@@ -358,37 +531,48 @@ func handleMultipartRun(w http.ResponseWriter, r *http.Request) {
 	if temperature > 0 {
 		opts.Temperature = temperature
 	}
+	opts.TargetImages = targetImages
+	opts.TargetDeadline = targetDeadline
+	opts.Trace = trace
+	opts.AspectRatio = aspectRatio
+	opts.Model = model
+	opts.CandidateCount = candidateCount
+
+	fmt.Printf("▶️ /run (multipart) files=%v processed=%v scenario=%d target=%d res=%s temp=%.1f promptLen=%d trace=%v\n", filenames, processed, opts.ScenarioCount, opts.TargetImages, opts.OutputRes, opts.Temperature, len(opts.PromptText), opts.Trace)
+	job := jobs.submit(opts, filenames, cleanup)
+	cleanup = nil
+	fmt.Printf("📥 /run (multipart) queued job=%s\n", job.ID)
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"status": job.Status,
+		"jobId":  job.ID,
+		"job":    job,
+	})
+}
 
-	var filename string
-	if header != nil {
-		filename = header.Filename
-	}
-	fmt.Printf("▶️ /run (multipart) file=%s processed=%s scenario=%d res=%s temp=%.1f promptLen=%d\n", filename, finalProcessPath, opts.ScenarioCount, opts.OutputRes, opts.Temperature, len(opts.PromptText))
-	results, runErr := runWithExclusive(r.Context(), opts)
-	if runErr != nil {
-		status := http.StatusInternalServerError
-		msg := runErr.Error()
-		if errors.Is(runErr, context.Canceled) {
-			status = http.StatusConflict
-			msg = "cancelled"
+func handleListJobs(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := strings.TrimSpace(r.URL.Query().Get("limit")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
 		}
-		fmt.Printf("⚠️ /run (multipart) end err=%v\n", runErr)
-		writeJSON(w, status, map[string]any{
-			"error":   msg,
-			"results": results,
-		})
-		return
 	}
-	fmt.Printf("✅ /run (multipart) done scenario=%d res=%s results=%d\n", opts.ScenarioCount, opts.OutputRes, len(results))
+	list := jobs.list(limit)
 	writeJSON(w, http.StatusOK, map[string]any{
-		"status":        "ok",
-		"imageUsed":     finalProcessPath,
-		"imageOrig":     filename,
-		"scenarioCount": opts.ScenarioCount,
-		"results":       results,
+		"count": len(list),
+		"jobs":  list,
 	})
 }
 
+func handleGetJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.PathValue("id"))
+	job, ok := jobs.get(id)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("job %s 不存在", id)})
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -546,7 +730,7 @@ func handleProxySubscriptions(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("save subs: %v", err)})
 			return
 		}
-		go proxy.WarmupSingBox(context.Background())
+		proxySupervisor.Reload()
 		writeJSON(w, http.StatusOK, map[string]any{
 			"subscriptions":       subs,
 			"storedSubscriptions": subs,
@@ -574,7 +758,7 @@ func handleProxySubscriptions(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("save subs: %v", err)})
 			return
 		}
-		go proxy.WarmupSingBox(context.Background())
+		proxySupervisor.Reload()
 		writeJSON(w, http.StatusOK, map[string]any{
 			"subscriptions":       cleaned,
 			"storedSubscriptions": cleaned,
@@ -604,7 +788,7 @@ func handleProxySubscriptions(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("save subs: %v", err)})
 			return
 		}
-		go proxy.WarmupSingBox(context.Background())
+		proxySupervisor.Reload()
 		writeJSON(w, http.StatusOK, map[string]any{
 			"subscriptions":       filtered,
 			"storedSubscriptions": filtered,
@@ -614,3 +798,56 @@ func handleProxySubscriptions(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET/POST/PUT/DELETE allowed"})
 	}
 }
+
+func handleListProxyNodes(w http.ResponseWriter, r *http.Request) {
+	nodes, err := proxySupervisor.Nodes()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	frozen := 0
+	for _, n := range nodes {
+		if n.Frozen {
+			frozen++
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"nodes": nodes, "total": len(nodes), "frozen": frozen})
+}
+
+// handleFreezeProxyNode 手动冻结节点；时长取 ?duration= 或 {"duration": "30m"}，缺省为 proxy.freezeDuration。
+func handleFreezeProxyNode(w http.ResponseWriter, r *http.Request) {
+	raw := strings.TrimSpace(r.URL.Query().Get("duration"))
+	if raw == "" {
+		var body struct {
+			Duration string `json:"duration"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		raw = strings.TrimSpace(body.Duration)
+	}
+	var d time.Duration
+	if raw != "" {
+		var err error
+		if d, err = time.ParseDuration(raw); err != nil || d <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid duration %q", raw)})
+			return
+		}
+	}
+	node, err := proxySupervisor.Freeze(r.PathValue("tag"), d)
+	writeNodeResult(w, node, err)
+}
+
+func handleUnfreezeProxyNode(w http.ResponseWriter, r *http.Request) {
+	node, err := proxySupervisor.Unfreeze(r.PathValue("tag"))
+	writeNodeResult(w, node, err)
+}
+
+func writeNodeResult(w http.ResponseWriter, node proxy.Node, err error) {
+	switch {
+	case errors.Is(err, proxy.ErrUnknownNode):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, node)
+	}
+}
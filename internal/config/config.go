@@ -0,0 +1,368 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"vertex-nano-banana-unlimited/internal/outcome"
+)
+
+// DefaultFile 是未设置 CONFIG_FILE 时读取的配置文件；文件不存在时使用默认值。
+const DefaultFile = "config.yaml"
+
+type Config struct {
+	Server ServerConfig `yaml:"server"`
+	Runner RunnerConfig `yaml:"runner"`
+	Proxy  ProxyConfig  `yaml:"proxy"`
+	Models ModelsConfig `yaml:"models"`
+}
+
+type ServerConfig struct {
+	Addr string `yaml:"addr"`
+}
+
+type RunnerConfig struct {
+	DownloadDir     string        `yaml:"downloadDir"`
+	StepPause       time.Duration `yaml:"stepPause"`
+	SubStepPause    time.Duration `yaml:"subStepPause"`
+	GotoTimeout     time.Duration `yaml:"gotoTimeout"`
+	TermsTimeout    time.Duration `yaml:"termsTimeout"`
+	DownloadWait    time.Duration `yaml:"downloadWait"`
+	TargetDeadline  time.Duration `yaml:"targetDeadline"`
+	MaxContexts     int           `yaml:"maxContexts"`
+	BrowserPoolSize int           `yaml:"browserPoolSize"`
+	ChromiumArgs    []string      `yaml:"chromiumArgs"`
+	SelectorsFile   string        `yaml:"selectorsFile"` // 页面定位器覆盖文件，不存在时使用内置定义
+}
+
+type ProxyConfig struct {
+	SingboxBasePort int           `yaml:"singboxBasePort"`
+	SingboxVersion  string        `yaml:"singboxVersion"`
+	FreezeDuration  time.Duration `yaml:"freezeDuration"`
+	// ProbeURL 通过每个节点请求的探测地址；ProbeInterval 为 0 时关闭健康检查。
+	ProbeURL      string        `yaml:"probeURL"`
+	ProbeInterval time.Duration `yaml:"probeInterval"`
+	ProbeTimeout  time.Duration `yaml:"probeTimeout"`
+	// Penalties 按场景结果设置节点冻结时长，未列出的结果使用 FreezeDuration；
+	// 失败类结果连续出现时时长按 2 的幂递增，最长 PenaltyMaxBackoff。
+	Penalties         map[string]time.Duration `yaml:"penalties"`
+	PenaltyMaxBackoff time.Duration            `yaml:"penaltyMaxBackoff"`
+}
+
+// PenaltyOutcomes 是 proxy.penalties 可配置的结果：全部场景结果代码以及 outcome.Error。
+var PenaltyOutcomes = func() []string {
+	out := make([]string, 0, len(outcome.All)+1)
+	for _, o := range outcome.All {
+		out = append(out, string(o))
+	}
+	return append(out, string(outcome.Error))
+}()
+
+// ModelsConfig 列出允许通过 /run 选择的模型；StudioURL 拼接 ?model=<id> 得到页面地址。
+type ModelsConfig struct {
+	Default   string   `yaml:"default"`
+	Supported []string `yaml:"supported"`
+	StudioURL string   `yaml:"studioURL"`
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{Addr: ":8080"},
+		Runner: RunnerConfig{
+			DownloadDir:     "tmp",
+			SelectorsFile:   "selectors.yaml",
+			StepPause:       time.Second,
+			SubStepPause:    500 * time.Millisecond,
+			GotoTimeout:     15 * time.Second,
+			TermsTimeout:    45 * time.Second,
+			DownloadWait:    720 * time.Second,
+			TargetDeadline:  30 * time.Minute,
+			MaxContexts:     8,
+			BrowserPoolSize: 1,
+			ChromiumArgs: []string{
+				"--start-maximized",
+				"--window-size=1920,1080",
+				"--autoplay-policy=no-user-gesture-required",
+				"--disable-features=IsolateOrigins,site-per-process,AutomationControlled",
+				"--disable-blink-features=AutomationControlled",
+				"--no-sandbox",
+				"--disable-dev-shm-usage",
+				"--incognito",
+			},
+		},
+		Proxy: ProxyConfig{
+			SingboxBasePort: 17880,
+			SingboxVersion:  "1.10.6",
+			FreezeDuration:  15 * time.Minute,
+			ProbeURL:        "https://www.gstatic.com/generate_204",
+			ProbeInterval:   2 * time.Minute,
+			ProbeTimeout:    10 * time.Second,
+			Penalties: map[string]time.Duration{
+				string(outcome.Downloaded):   5 * time.Minute,
+				string(outcome.Exhausted):    15 * time.Minute,
+				string(outcome.TokenInvalid): 30 * time.Minute,
+				string(outcome.SubmitFailed): 5 * time.Minute,
+				string(outcome.Deadline):     3 * time.Minute,
+				string(outcome.Timeout):      time.Minute,
+				string(outcome.Cancelled):    time.Minute,
+				string(outcome.Blocked):      0,
+				string(outcome.Text):         0,
+				string(outcome.None):         time.Minute,
+				string(outcome.Error):        5 * time.Minute,
+			},
+			PenaltyMaxBackoff: 4 * time.Hour,
+		},
+		Models: ModelsConfig{
+			Default:   "gemini-3-pro-image-preview",
+			Supported: []string{"gemini-3-pro-image-preview", "gemini-2.5-flash-image"},
+			StudioURL: "https://console.cloud.google.com/vertex-ai/studio/multimodal;mode=prompt",
+		},
+	}
+}
+
+// Load 依次应用默认值、配置文件（CONFIG_FILE 或 config.yaml）和环境变量覆盖，并校验结果。
+func Load() (Config, error) {
+	path := strings.TrimSpace(os.Getenv("CONFIG_FILE"))
+	explicit := path != ""
+	if !explicit {
+		path = DefaultFile
+	}
+	cfg := Default()
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := decode(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+		}
+	case os.IsNotExist(err) && !explicit:
+	default:
+		return cfg, fmt.Errorf("读取配置文件 %s 失败: %w", path, err)
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("配置无效:\n%w", err)
+	}
+	return cfg, nil
+}
+
+func decode(data []byte, cfg *Config) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// envOverride 把一个环境变量映射到配置字段，set 负责解析。
+type envOverride struct {
+	name string
+	set  func(cfg *Config, v string) error
+}
+
+var envOverrides = []envOverride{
+	{"SERVER_ADDR", func(c *Config, v string) error { c.Server.Addr = v; return nil }},
+	{"DEFAULT_DOWNLOAD_DIR", func(c *Config, v string) error { c.Runner.DownloadDir = v; return nil }},
+	{"STEP_PAUSE", durationEnv(func(c *Config) *time.Duration { return &c.Runner.StepPause })},
+	{"SUB_STEP_PAUSE", durationEnv(func(c *Config) *time.Duration { return &c.Runner.SubStepPause })},
+	{"GOTO_TIMEOUT", durationEnv(func(c *Config) *time.Duration { return &c.Runner.GotoTimeout })},
+	{"TERMS_TIMEOUT", durationEnv(func(c *Config) *time.Duration { return &c.Runner.TermsTimeout })},
+	{"DOWNLOAD_WAIT", durationEnv(func(c *Config) *time.Duration { return &c.Runner.DownloadWait })},
+	{"TARGET_DEADLINE", durationEnv(func(c *Config) *time.Duration { return &c.Runner.TargetDeadline })},
+	{"MAX_BROWSER_CONTEXTS", intEnv(func(c *Config) *int { return &c.Runner.MaxContexts })},
+	{"BROWSER_POOL_SIZE", intEnv(func(c *Config) *int { return &c.Runner.BrowserPoolSize })},
+	// Chromium 参数本身可能含逗号，因此按空白分隔
+	{"CHROMIUM_ARGS", func(c *Config, v string) error { c.Runner.ChromiumArgs = strings.Fields(v); return nil }},
+	{"SELECTORS_FILE", func(c *Config, v string) error { c.Runner.SelectorsFile = v; return nil }},
+	{"SINGBOX_BASE_PORT", intEnv(func(c *Config) *int { return &c.Proxy.SingboxBasePort })},
+	{"SINGBOX_VERSION", func(c *Config, v string) error { c.Proxy.SingboxVersion = v; return nil }},
+	{"PROXY_FREEZE_DURATION", durationEnv(func(c *Config) *time.Duration { return &c.Proxy.FreezeDuration })},
+	// 逗号分隔的 结果=时长，只覆盖列出的结果，例如 downloaded=3m,exhausted=20m
+	{"PROXY_PENALTIES", func(c *Config, v string) error {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			outcome, dur, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("%q 应为 结果=时长", item)
+			}
+			d, err := time.ParseDuration(strings.TrimSpace(dur))
+			if err != nil {
+				return err
+			}
+			if c.Proxy.Penalties == nil {
+				c.Proxy.Penalties = map[string]time.Duration{}
+			}
+			c.Proxy.Penalties[strings.TrimSpace(outcome)] = d
+		}
+		return nil
+	}},
+	{"PROXY_PENALTY_MAX_BACKOFF", durationEnv(func(c *Config) *time.Duration { return &c.Proxy.PenaltyMaxBackoff })},
+	{"PROXY_PROBE_URL", func(c *Config, v string) error { c.Proxy.ProbeURL = v; return nil }},
+	{"PROXY_PROBE_INTERVAL", durationEnv(func(c *Config) *time.Duration { return &c.Proxy.ProbeInterval })},
+	{"PROXY_PROBE_TIMEOUT", durationEnv(func(c *Config) *time.Duration { return &c.Proxy.ProbeTimeout })},
+	{"DEFAULT_MODEL", func(c *Config, v string) error { c.Models.Default = v; return nil }},
+	{"SUPPORTED_MODELS", func(c *Config, v string) error {
+		c.Models.Supported = nil
+		for _, m := range strings.Split(v, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				c.Models.Supported = append(c.Models.Supported, m)
+			}
+		}
+		return nil
+	}},
+	{"STUDIO_URL", func(c *Config, v string) error { c.Models.StudioURL = v; return nil }},
+}
+
+func durationEnv(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+func intEnv(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func applyEnv(cfg *Config) error {
+	var errs []error
+	for _, o := range envOverrides {
+		v := strings.TrimSpace(os.Getenv(o.name))
+		if v == "" {
+			continue
+		}
+		if err := o.set(cfg, v); err != nil {
+			errs = append(errs, fmt.Errorf("环境变量 %s=%q 无效: %w", o.name, v, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var versionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.]+)?$`)
+
+// Validate 检查所有字段，一次性返回全部问题。
+func (c Config) Validate() error {
+	var errs []error
+	bad := func(field string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("  - %s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if _, port, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		bad("server.addr", "%q 不是合法的监听地址（例如 :8080）: %v", c.Server.Addr, err)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		bad("server.addr", "端口 %q 无效", port)
+	}
+
+	r := c.Runner
+	if strings.TrimSpace(r.DownloadDir) == "" {
+		bad("runner.downloadDir", "不能为空")
+	}
+	for _, d := range []struct {
+		field string
+		value time.Duration
+	}{
+		{"runner.stepPause", r.StepPause},
+		{"runner.subStepPause", r.SubStepPause},
+		{"runner.gotoTimeout", r.GotoTimeout},
+		{"runner.termsTimeout", r.TermsTimeout},
+		{"runner.downloadWait", r.DownloadWait},
+		{"runner.targetDeadline", r.TargetDeadline},
+	} {
+		if d.value <= 0 {
+			bad(d.field, "必须大于 0，当前为 %s", d.value)
+		}
+	}
+	if r.MaxContexts < 1 {
+		bad("runner.maxContexts", "必须 >= 1，当前为 %d", r.MaxContexts)
+	}
+	if r.BrowserPoolSize < 1 {
+		bad("runner.browserPoolSize", "必须 >= 1，当前为 %d", r.BrowserPoolSize)
+	}
+	for i, arg := range r.ChromiumArgs {
+		if !strings.HasPrefix(arg, "--") {
+			bad(fmt.Sprintf("runner.chromiumArgs[%d]", i), "%q 应以 -- 开头", arg)
+		}
+	}
+
+	p := c.Proxy
+	if p.SingboxBasePort < 1024 || p.SingboxBasePort > 65535 {
+		bad("proxy.singboxBasePort", "需在 1024-65535 之间，当前为 %d", p.SingboxBasePort)
+	}
+	if !versionPattern.MatchString(p.SingboxVersion) {
+		bad("proxy.singboxVersion", "%q 不是合法的版本号（例如 1.10.6）", p.SingboxVersion)
+	}
+	if p.FreezeDuration < 0 {
+		bad("proxy.freezeDuration", "不能为负数，当前为 %s", p.FreezeDuration)
+	}
+	for _, outcome := range sortedKeys(p.Penalties) {
+		if !slices.Contains(PenaltyOutcomes, outcome) {
+			bad("proxy.penalties", "未知结果 %q，可选: %s", outcome, strings.Join(PenaltyOutcomes, ", "))
+		} else if d := p.Penalties[outcome]; d < 0 {
+			bad("proxy.penalties."+outcome, "不能为负数，当前为 %s", d)
+		}
+	}
+	if p.PenaltyMaxBackoff <= 0 {
+		bad("proxy.penaltyMaxBackoff", "必须大于 0，当前为 %s", p.PenaltyMaxBackoff)
+	}
+	if p.ProbeInterval < 0 {
+		bad("proxy.probeInterval", "不能为负数，当前为 %s", p.ProbeInterval)
+	}
+	if p.ProbeInterval > 0 {
+		if u, err := url.Parse(p.ProbeURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			bad("proxy.probeURL", "%q 不是合法的 http(s) 地址", p.ProbeURL)
+		}
+		if p.ProbeTimeout <= 0 {
+			bad("proxy.probeTimeout", "必须大于 0，当前为 %s", p.ProbeTimeout)
+		}
+	}
+
+	m := c.Models
+	if len(m.Supported) == 0 {
+		bad("models.supported", "至少需要一个模型")
+	}
+	if !slices.Contains(m.Supported, m.Default) {
+		bad("models.default", "%q 不在 models.supported 中", m.Default)
+	}
+	if u, err := url.Parse(m.StudioURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		bad("models.studioURL", "%q 不是合法的 http(s) 地址", m.StudioURL)
+	} else if u.RawQuery != "" {
+		bad("models.studioURL", "不能包含查询参数，模型通过 ?model= 追加")
+	}
+	return errors.Join(errs...)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
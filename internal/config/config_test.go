@@ -0,0 +1,185 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv 清空所有覆盖变量，避免宿主环境影响测试。
+func clearEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	for _, o := range envOverrides {
+		t.Setenv(o.name, "")
+	}
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("Default().Validate() = %v", err)
+	}
+	for _, outcome := range sortedKeys(Default().Proxy.Penalties) {
+		if !slices.Contains(PenaltyOutcomes, outcome) {
+			t.Errorf("default penalty for unknown outcome %q", outcome)
+		}
+	}
+	// 每个结果都有默认冻结时长，不会落到 freezeDuration
+	for _, outcome := range PenaltyOutcomes {
+		if _, ok := Default().Proxy.Penalties[outcome]; !ok {
+			t.Errorf("no default penalty for outcome %q", outcome)
+		}
+	}
+}
+
+func TestLoadDefaultsWithoutFile(t *testing.T) {
+	clearEnv(t)
+	// 包目录下没有 config.yaml，Load 只应用默认值
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Load() without config file differs from Default():\n%+v", cfg)
+	}
+}
+
+func TestLoadFileAndEnv(t *testing.T) {
+	clearEnv(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "custom.yaml")
+	data := "server:\n  addr: \":9090\"\nrunner:\n  maxContexts: 3\nproxy:\n  penalties:\n    exhausted: 1h\n"
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("MAX_BROWSER_CONTEXTS", "5")
+	t.Setenv("STEP_PAUSE", "250ms")
+	t.Setenv("CHROMIUM_ARGS", "--a=1,2  --b")
+	t.Setenv("SUPPORTED_MODELS", " m1 , ,m2")
+	t.Setenv("DEFAULT_MODEL", "m2")
+	t.Setenv("PROXY_PENALTIES", "downloaded=3m, text=1m")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"server.addr from file", cfg.Server.Addr, ":9090"},
+		{"env overrides file", cfg.Runner.MaxContexts, 5},
+		{"duration env", cfg.Runner.StepPause, 250 * time.Millisecond},
+		{"chromium args split on whitespace", cfg.Runner.ChromiumArgs, []string{"--a=1,2", "--b"}},
+		{"models split on commas", cfg.Models.Supported, []string{"m1", "m2"}},
+		{"default model", cfg.Models.Default, "m2"},
+		{"penalty from file", cfg.Proxy.Penalties["exhausted"], time.Hour},
+		{"penalty from env", cfg.Proxy.Penalties["downloaded"], 3 * time.Minute},
+		{"penalty env adds key", cfg.Proxy.Penalties["text"], time.Minute},
+		{"unlisted penalty keeps default", cfg.Proxy.Penalties["deadline"], 3 * time.Minute},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		file string // 非空时写入 CONFIG_FILE
+		want []string
+	}{
+		{"bad duration env", map[string]string{"GOTO_TIMEOUT": "soon"}, "", []string{"GOTO_TIMEOUT"}},
+		{"bad int env", map[string]string{"SINGBOX_BASE_PORT": "x"}, "", []string{"SINGBOX_BASE_PORT"}},
+		{"bad penalties env", map[string]string{"PROXY_PENALTIES": "exhausted"}, "", []string{"PROXY_PENALTIES", "结果=时长"}},
+		{"all env errors reported", map[string]string{"GOTO_TIMEOUT": "soon", "MAX_BROWSER_CONTEXTS": "many"}, "", []string{"GOTO_TIMEOUT", "MAX_BROWSER_CONTEXTS"}},
+		{"unknown yaml field", nil, "runner:\n  maxContext: 3\n", []string{"maxContext"}},
+		{"missing explicit file", map[string]string{"CONFIG_FILE": "/nonexistent/config.yaml"}, "", []string{"/nonexistent/config.yaml"}},
+		{"validation after env", map[string]string{"MAX_BROWSER_CONTEXTS": "0"}, "", []string{"runner.maxContexts"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			if tt.file != "" {
+				file := filepath.Join(t.TempDir(), "custom.yaml")
+				if err := os.WriteFile(file, []byte(tt.file), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv("CONFIG_FILE", file)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := Load()
+			if err == nil {
+				t.Fatal("Load() succeeded, want error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   []string // 错误中应出现的字段
+	}{
+		{"bad addr", func(c *Config) { c.Server.Addr = "8080" }, []string{"server.addr"}},
+		{"bad port", func(c *Config) { c.Server.Addr = ":70000" }, []string{"server.addr"}},
+		{"empty download dir", func(c *Config) { c.Runner.DownloadDir = " " }, []string{"runner.downloadDir"}},
+		{"zero durations", func(c *Config) { c.Runner.GotoTimeout = 0; c.Runner.DownloadWait = -time.Second }, []string{"runner.gotoTimeout", "runner.downloadWait"}},
+		{"contexts and pool", func(c *Config) { c.Runner.MaxContexts = 0; c.Runner.BrowserPoolSize = 0 }, []string{"runner.maxContexts", "runner.browserPoolSize"}},
+		{"chromium arg", func(c *Config) { c.Runner.ChromiumArgs = []string{"--ok", "bad"} }, []string{"runner.chromiumArgs[1]"}},
+		{"singbox port", func(c *Config) { c.Proxy.SingboxBasePort = 80 }, []string{"proxy.singboxBasePort"}},
+		{"singbox version", func(c *Config) { c.Proxy.SingboxVersion = "latest" }, []string{"proxy.singboxVersion"}},
+		{"negative freeze", func(c *Config) { c.Proxy.FreezeDuration = -time.Minute }, []string{"proxy.freezeDuration"}},
+		{"unknown penalty outcome", func(c *Config) { c.Proxy.Penalties["exausted"] = time.Minute }, []string{"proxy.penalties", "exausted"}},
+		{"negative penalty", func(c *Config) { c.Proxy.Penalties["text"] = -time.Minute }, []string{"proxy.penalties.text"}},
+		{"max backoff", func(c *Config) { c.Proxy.PenaltyMaxBackoff = 0 }, []string{"proxy.penaltyMaxBackoff"}},
+		{"probe url", func(c *Config) { c.Proxy.ProbeURL = "gstatic.com" }, []string{"proxy.probeURL"}},
+		{"probe timeout", func(c *Config) { c.Proxy.ProbeTimeout = 0 }, []string{"proxy.probeTimeout"}},
+		{"negative probe interval", func(c *Config) { c.Proxy.ProbeInterval = -time.Second }, []string{"proxy.probeInterval"}},
+		{"no models", func(c *Config) { c.Models.Supported = nil }, []string{"models.supported", "models.default"}},
+		{"default not supported", func(c *Config) { c.Models.Default = "other" }, []string{"models.default"}},
+		{"studio url query", func(c *Config) { c.Models.StudioURL += "?model=x" }, []string{"models.studioURL"}},
+		{"studio url scheme", func(c *Config) { c.Models.StudioURL = "ftp://example.com" }, []string{"models.studioURL"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.mutate(&cfg)
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Validate() succeeded, want error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSkipsProbeFieldsWhenDisabled(t *testing.T) {
+	cfg := Default()
+	cfg.Proxy.ProbeInterval = 0
+	cfg.Proxy.ProbeURL = ""
+	cfg.Proxy.ProbeTimeout = 0
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with probing disabled = %v", err)
+	}
+}
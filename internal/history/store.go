@@ -0,0 +1,196 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record 是一次场景执行（或未能启动场景的整次运行）的持久化记录。
+type Record struct {
+	JobID          string    `json:"jobId"`
+	ScenarioID     int       `json:"scenarioId"`
+	JobStatus      string    `json:"jobStatus"`
+	Prompt         string    `json:"prompt"`
+	Model          string    `json:"model,omitempty"`
+	ImageOrig      string    `json:"imageOrig,omitempty"`
+	ImageUsed      string    `json:"imageUsed,omitempty"`
+	ImagesOrig     []string  `json:"imagesOrig,omitempty"` // 全部参考图片，ImageOrig/ImageUsed 为第一张
+	ImagesUsed     []string  `json:"imagesUsed,omitempty"`
+	OutputRes      string    `json:"outputRes,omitempty"`
+	Temperature    float64   `json:"temperature,omitempty"`
+	AspectRatio    string    `json:"aspectRatio,omitempty"`
+	CandidateCount int       `json:"candidateCount,omitempty"`
+	ProxyTag       string    `json:"proxyTag,omitempty"`
+	Outcome        string    `json:"outcome,omitempty"`
+	Path           string    `json:"path,omitempty"`
+	Paths          []string  `json:"paths,omitempty"`
+	URL            string    `json:"url,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	FailedStep     string    `json:"failedStep,omitempty"`
+	Attempts       int       `json:"attempts,omitempty"`
+	DebugURL       string    `json:"debugUrl,omitempty"`
+	TraceURL       string    `json:"traceUrl,omitempty"`
+	Text           string    `json:"text,omitempty"`
+	BlockReason    string    `json:"blockReason,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	FinishedAt     time.Time `json:"finishedAt"`
+}
+
+// Filter 描述历史查询条件；字符串字段为空表示不过滤。
+type Filter struct {
+	JobID    string
+	Prompt   string // 子串匹配，忽略大小写
+	Outcome  string
+	ProxyTag string
+	Path     string // 子串匹配，可用于反查某张图片
+	Since    time.Time
+	Until    time.Time
+	Offset   int
+	Limit    int
+}
+
+// Page 是一次分页查询的结果，Records 按完成时间倒序排列。
+type Page struct {
+	Total   int      `json:"total"`
+	Offset  int      `json:"offset"`
+	Limit   int      `json:"limit"`
+	Records []Record `json:"records"`
+}
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+// Store 是基于 JSONL 追加日志的历史存储，进程重启后依然可查。
+type Store struct {
+	mu   sync.Mutex
+	path string
+}
+
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Append 追加若干记录到日志末尾。
+func (s *Store) Append(records ...Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("make history dir: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open history: %w", err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for _, r := range records {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("marshal history: %w", err)
+		}
+		_, _ = w.Write(data)
+		_ = w.WriteByte('\n')
+	}
+	return w.Flush()
+}
+
+// Query 扫描日志并返回符合条件的分页结果。损坏的行会被跳过。
+func (s *Store) Query(f Filter) (Page, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultLimit
+	}
+	if f.Limit > maxLimit {
+		f.Limit = maxLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	page := Page{Offset: f.Offset, Limit: f.Limit, Records: []Record{}}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return page, nil
+		}
+		return page, fmt.Errorf("open history: %w", err)
+	}
+	defer file.Close()
+
+	var matched []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			continue
+		}
+		if f.match(r) {
+			matched = append(matched, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return page, fmt.Errorf("read history: %w", err)
+	}
+
+	// 日志按追加顺序存储，倒序即为最新在前
+	page.Total = len(matched)
+	for i := len(matched) - 1 - f.Offset; i >= 0 && len(page.Records) < f.Limit; i-- {
+		page.Records = append(page.Records, matched[i])
+	}
+	return page, nil
+}
+
+func (f Filter) match(r Record) bool {
+	if f.JobID != "" && r.JobID != f.JobID {
+		return false
+	}
+	if f.Outcome != "" && r.Outcome != f.Outcome {
+		return false
+	}
+	if f.ProxyTag != "" && r.ProxyTag != f.ProxyTag {
+		return false
+	}
+	if f.Prompt != "" && !strings.Contains(strings.ToLower(r.Prompt), strings.ToLower(f.Prompt)) {
+		return false
+	}
+	if f.Path != "" && !matchPath(r, filepath.ToSlash(f.Path)) {
+		return false
+	}
+	if !f.Since.IsZero() && r.FinishedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && r.FinishedAt.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+func matchPath(r Record, sub string) bool {
+	for _, p := range append([]string{r.Path}, r.Paths...) {
+		if strings.Contains(filepath.ToSlash(p), sub) {
+			return true
+		}
+	}
+	return false
+}
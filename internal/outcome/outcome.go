@@ -0,0 +1,39 @@
+// Package outcome 定义场景结果代码。它不依赖其他包，steps 产生结果，config 与 proxy 按结果配置和执行节点冻结。
+package outcome
+
+type Outcome string
+
+const (
+	Downloaded   Outcome = "downloaded"
+	Exhausted    Outcome = "exhausted"     // 429 / 配额用尽
+	Deadline     Outcome = "deadline"      // 服务端 Deadline expired
+	Timeout      Outcome = "timeout"       // 本地等待超过 maxWait，生成慢或 maxWait 过小，不算节点失败
+	Cancelled    Outcome = "cancelled"     // 服务端提示操作被取消
+	SubmitFailed Outcome = "submit_failed" // 提示词未能提交
+	TokenInvalid Outcome = "token_invalid" // Recaptcha token 无效
+	Blocked      Outcome = "blocked"       // 内容被安全策略拦截
+	Text         Outcome = "text"          // 模型只回复了文本，没有图片
+	None         Outcome = "none"
+
+	// Error 是下载之前的步骤（导航、上传等）失败时记录的结果，不属于 All。
+	Error Outcome = "error"
+)
+
+// All 列出全部场景结果，proxy.penalties 的可选值由它与 Error 派生。
+var All = []Outcome{Downloaded, Exhausted, Deadline, Timeout, Cancelled, SubmitFailed, TokenInvalid, Blocked, Text, None}
+
+// Failed 报告结果是否为页面或接口明确给出的失败（不含 downloaded / none）。
+func (o Outcome) Failed() bool {
+	return o != Downloaded && o != None
+}
+
+// NodeFailure 报告结果是否可能与所用节点有关（配额、服务端超时、提交失败、步骤失败等）。
+// 内容拦截与只回复文本取决于提示词，不算节点失败。
+func (o Outcome) NodeFailure() bool {
+	switch o {
+	case Exhausted, Deadline, Cancelled, SubmitFailed, TokenInvalid, Error:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,396 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// clashSkip 记录一个无法转换的 Clash 节点及原因。
+type clashSkip struct {
+	Name   string
+	Type   string
+	Reason string
+}
+
+// parseClashSubscription 尝试把 content（或其 base64 解码结果）当作 Clash/Mihomo YAML 解析。
+// ok 为 false 表示内容不是带 proxies 列表的 Clash 配置；无法转换的节点会打印出来。
+func parseClashSubscription(content []byte) (out []map[string]any, ok bool, err error) {
+	proxies, ok := clashProxies(content)
+	if !ok {
+		dec, derr := decodeBase64Loose(string(content))
+		if derr != nil {
+			return nil, false, nil
+		}
+		if proxies, ok = clashProxies(dec); !ok {
+			return nil, false, nil
+		}
+	}
+	out, skipped := convertClashProxies(proxies)
+	for _, s := range skipped {
+		fmt.Printf("⏭️ 跳过 Clash 节点 %s (%s): %s\n", s.Name, s.Type, s.Reason)
+	}
+	fmt.Printf("🧭 Clash 订阅：转换 %d 个节点，跳过 %d 个\n", len(out), len(skipped))
+	if len(out) == 0 {
+		return nil, true, fmt.Errorf("Clash 订阅中没有可转换的节点（共 %d 个）", len(proxies))
+	}
+	return out, true, nil
+}
+
+func clashProxies(data []byte) ([]map[string]any, bool) {
+	if !bytes.Contains(data, []byte("proxies")) {
+		return nil, false
+	}
+	var doc struct {
+		Proxies []map[string]any `yaml:"proxies"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil || doc.Proxies == nil {
+		return nil, false
+	}
+	return doc.Proxies, true
+}
+
+// convertClashProxies 把 Clash proxies 列表转换为 sing-box outbounds，返回无法转换的条目。
+func convertClashProxies(proxies []map[string]any) ([]map[string]any, []clashSkip) {
+	var out []map[string]any
+	var skipped []clashSkip
+	for i, p := range proxies {
+		name := clashString(p, "name")
+		if name == "" {
+			name = fmt.Sprintf("clash-%d", i+1)
+		}
+		typ := strings.ToLower(clashString(p, "type"))
+		ob, err := convertClashProxy(p, typ)
+		if err != nil {
+			skipped = append(skipped, clashSkip{Name: name, Type: typ, Reason: err.Error()})
+			continue
+		}
+		ob["tag"] = name
+		out = append(out, ob)
+	}
+	return out, skipped
+}
+
+func convertClashProxy(p map[string]any, typ string) (map[string]any, error) {
+	server := clashString(p, "server")
+	port := clashInt(p, "port")
+	if server == "" || port <= 0 {
+		return nil, errors.New("缺少 server 或 port")
+	}
+	ob := map[string]any{"server": server, "server_port": port}
+
+	var need []string // 必填的字符串字段
+	switch typ {
+	case "ss":
+		need = []string{"method", "password"}
+		ob["type"] = "shadowsocks"
+		ob["method"] = clashString(p, "cipher")
+		ob["password"] = clashString(p, "password")
+		if clashBool(p, "udp-over-tcp") {
+			ob["udp_over_tcp"] = true
+		}
+		if plugin := clashString(p, "plugin"); plugin != "" {
+			name, opts, err := clashSSPlugin(plugin, clashMap(p, "plugin-opts"))
+			if err != nil {
+				return nil, err
+			}
+			ob["plugin"], ob["plugin_opts"] = name, opts
+		}
+	case "vmess":
+		need = []string{"uuid"}
+		ob["type"] = "vmess"
+		ob["uuid"] = clashString(p, "uuid")
+		ob["alter_id"] = clashInt(p, "alterId")
+		ob["security"] = clashStringOr(p, "cipher", "auto")
+	case "vless":
+		need = []string{"uuid"}
+		ob["type"] = "vless"
+		ob["uuid"] = clashString(p, "uuid")
+		if flow := clashString(p, "flow"); flow != "" {
+			ob["flow"] = flow
+		}
+	case "trojan":
+		need = []string{"password"}
+		ob["type"] = "trojan"
+		ob["password"] = clashString(p, "password")
+	case "hysteria2":
+		need = []string{"password"}
+		ob["type"] = "hysteria2"
+		ob["password"] = clashString(p, "password")
+		if up := clashMbps(p, "up"); up > 0 {
+			ob["up_mbps"] = up
+		}
+		if down := clashMbps(p, "down"); down > 0 {
+			ob["down_mbps"] = down
+		}
+		if obfs := clashString(p, "obfs"); obfs != "" {
+			ob["obfs"] = map[string]any{"type": obfs, "password": clashString(p, "obfs-password")}
+		}
+		ob["tls"] = clashTLS(p, true)
+	case "tuic":
+		if clashString(p, "token") != "" {
+			return nil, errors.New("不支持 TUIC v4（token）")
+		}
+		need = []string{"uuid"}
+		ob["type"] = "tuic"
+		ob["uuid"] = clashString(p, "uuid")
+		ob["password"] = clashString(p, "password")
+		if cc := clashString(p, "congestion-controller"); cc != "" {
+			ob["congestion_control"] = cc
+		}
+		if mode := clashString(p, "udp-relay-mode"); mode != "" {
+			ob["udp_relay_mode"] = mode
+		}
+		if clashBool(p, "reduce-rtt") {
+			ob["zero_rtt_handshake"] = true
+		}
+		if hb := clashInt(p, "heartbeat-interval"); hb > 0 {
+			ob["heartbeat"] = fmt.Sprintf("%dms", hb)
+		}
+		ob["tls"] = clashTLS(p, true)
+	case "":
+		return nil, errors.New("缺少 type")
+	default:
+		return nil, fmt.Errorf("不支持的类型 %q", typ)
+	}
+
+	if err := requireFields(ob, need...); err != nil {
+		return nil, err
+	}
+	// vmess / vless / trojan 共用 TLS 与传输层设置
+	if typ == "vmess" || typ == "vless" || typ == "trojan" {
+		if tls := clashTLS(p, typ == "trojan"); tls != nil {
+			ob["tls"] = tls
+		}
+		transport, err := clashTransport(p)
+		if err != nil {
+			return nil, err
+		}
+		if transport != nil {
+			ob["transport"] = transport
+		}
+	}
+	return ob, nil
+}
+
+// requireFields 检查 ob 中的字符串字段均不为空。
+func requireFields(ob map[string]any, keys ...string) error {
+	for _, k := range keys {
+		if s, _ := ob[k].(string); s == "" {
+			return fmt.Errorf("缺少 %s", k)
+		}
+	}
+	return nil
+}
+
+// clashTLS 生成 sing-box tls 段；always 为 false 且未开启 tls 时返回 nil。
+func clashTLS(p map[string]any, always bool) map[string]any {
+	reality := clashMap(p, "reality-opts")
+	if !always && !clashBool(p, "tls") && reality == nil {
+		return nil
+	}
+	tls := map[string]any{"enabled": true}
+	if sni := clashString(p, "servername"); sni != "" {
+		tls["server_name"] = sni
+	} else if sni := clashString(p, "sni"); sni != "" {
+		tls["server_name"] = sni
+	}
+	if clashBool(p, "skip-cert-verify") {
+		tls["insecure"] = true
+	}
+	if alpn := clashStrings(p, "alpn"); len(alpn) > 0 {
+		tls["alpn"] = alpn
+	}
+	if fp := clashString(p, "client-fingerprint"); fp != "" {
+		tls["utls"] = map[string]any{"enabled": true, "fingerprint": fp}
+	}
+	if reality != nil {
+		tls["reality"] = map[string]any{
+			"enabled":    true,
+			"public_key": clashString(reality, "public-key"),
+			"short_id":   clashString(reality, "short-id"),
+		}
+		if _, ok := tls["utls"]; !ok {
+			// sing-box 的 reality 需要 uTLS
+			tls["utls"] = map[string]any{"enabled": true, "fingerprint": "chrome"}
+		}
+	}
+	return tls
+}
+
+// clashTransport 转换 network 与对应的 *-opts；tcp 返回 nil。
+func clashTransport(p map[string]any) (map[string]any, error) {
+	switch network := strings.ToLower(clashString(p, "network")); network {
+	case "", "tcp":
+		return nil, nil
+	case "ws":
+		opts := clashMap(p, "ws-opts")
+		t := map[string]any{"type": "ws", "path": clashStringOr(opts, "path", clashStringOr(p, "ws-path", "/"))}
+		headers := clashMap(opts, "headers")
+		if headers == nil {
+			headers = clashMap(p, "ws-headers")
+		}
+		if len(headers) > 0 {
+			t["headers"] = headers
+		}
+		if n := clashInt(opts, "max-early-data"); n > 0 {
+			t["max_early_data"] = n
+			t["early_data_header_name"] = clashStringOr(opts, "early-data-header-name", "Sec-WebSocket-Protocol")
+		}
+		return t, nil
+	case "grpc":
+		return map[string]any{"type": "grpc", "service_name": clashString(clashMap(p, "grpc-opts"), "grpc-service-name")}, nil
+	case "h2":
+		opts := clashMap(p, "h2-opts")
+		t := map[string]any{"type": "http", "path": clashStringOr(opts, "path", "/")}
+		if hosts := clashStrings(opts, "host"); len(hosts) > 0 {
+			t["host"] = hosts
+		}
+		return t, nil
+	case "http":
+		opts := clashMap(p, "http-opts")
+		t := map[string]any{"type": "http"}
+		if paths := clashStrings(opts, "path"); len(paths) > 0 {
+			t["path"] = paths[0]
+		}
+		if method := clashString(opts, "method"); method != "" {
+			t["method"] = method
+		}
+		if headers := clashMap(opts, "headers"); len(headers) > 0 {
+			t["headers"] = headers
+			if hosts := clashStrings(headers, "Host"); len(hosts) > 0 {
+				t["host"] = hosts
+			}
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("不支持的传输 %q", network)
+	}
+}
+
+// clashSSPlugin 把 Clash 的 obfs / v2ray-plugin 转换为 sing-box 的 plugin 与 plugin_opts。
+func clashSSPlugin(plugin string, opts map[string]any) (string, string, error) {
+	switch plugin {
+	case "obfs":
+		parts := []string{"obfs=" + clashStringOr(opts, "mode", "http")}
+		if host := clashString(opts, "host"); host != "" {
+			parts = append(parts, "obfs-host="+host)
+		}
+		return "obfs-local", strings.Join(parts, ";"), nil
+	case "v2ray-plugin":
+		if mode := clashStringOr(opts, "mode", "websocket"); mode != "websocket" {
+			return "", "", fmt.Errorf("v2ray-plugin 不支持 mode=%s", mode)
+		}
+		parts := []string{"mode=websocket"}
+		if clashBool(opts, "tls") {
+			parts = append(parts, "tls")
+		}
+		if host := clashString(opts, "host"); host != "" {
+			parts = append(parts, "host="+host)
+		}
+		if path := clashString(opts, "path"); path != "" {
+			parts = append(parts, "path="+path)
+		}
+		return "v2ray-plugin", strings.Join(parts, ";"), nil
+	default:
+		return "", "", fmt.Errorf("不支持的 ss 插件 %q", plugin)
+	}
+}
+
+// ------------------ Clash 字段读取 ------------------
+// Clash 配置中同一字段常见多种写法（端口写成字符串、布尔写成 "true" 等），这里统一宽松读取。
+
+func clashString(m map[string]any, key string) string {
+	switch v := m[key].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case int, int64, float64, bool:
+		return fmt.Sprint(v)
+	default:
+		return ""
+	}
+}
+
+func clashStringOr(m map[string]any, key, def string) string {
+	if v := clashString(m, key); v != "" {
+		return v
+	}
+	return def
+}
+
+func clashInt(m map[string]any, key string) int {
+	switch v := m[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case string:
+		n, _ := strconv.Atoi(strings.TrimSpace(v))
+		return n
+	default:
+		return 0
+	}
+}
+
+func clashBool(m map[string]any, key string) bool {
+	switch v := m[key].(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(strings.TrimSpace(v))
+		return b
+	default:
+		return false
+	}
+}
+
+// clashStrings 读取字符串或字符串列表。
+func clashStrings(m map[string]any, key string) []string {
+	switch v := m[key].(type) {
+	case string:
+		if v = strings.TrimSpace(v); v != "" {
+			return []string{v}
+		}
+	case []any:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				out = append(out, strings.TrimSpace(s))
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func clashMap(m map[string]any, key string) map[string]any {
+	v, _ := m[key].(map[string]any)
+	return v
+}
+
+// clashMbps 解析 "100"、"100 Mbps" 或数字形式的带宽。
+func clashMbps(m map[string]any, key string) int {
+	s := strings.ToLower(clashString(m, key))
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(s, "mbps"), "m"))
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// decodeBase64Loose 依次尝试标准、无填充与 URL 安全的 base64，忽略换行。
+func decodeBase64Loose(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	var lastErr error
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		dec, err := enc.DecodeString(s)
+		if err == nil {
+			return dec, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,155 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 节点样例取自常见机场下发的 Clash/Mihomo 配置，仅替换了地址与密钥。
+func TestConvertClashProxy(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want map[string]any
+		skip string // 非空时期望跳过，且原因包含该字符串
+	}{
+		{
+			name: "ss obfs",
+			yaml: `{name: "🇭🇰 香港 01", type: ss, server: hk01.example.net, port: 40001, cipher: chacha20-ietf-poly1305, password: "p@ss/w0rd", udp: true, plugin: obfs, plugin-opts: {mode: tls, host: download.windowsupdate.com}}`,
+			want: map[string]any{
+				"type": "shadowsocks", "tag": "🇭🇰 香港 01", "server": "hk01.example.net", "server_port": 40001,
+				"method": "chacha20-ietf-poly1305", "password": "p@ss/w0rd",
+				"plugin": "obfs-local", "plugin_opts": "obfs=tls;obfs-host=download.windowsupdate.com",
+			},
+		},
+		{
+			name: "ss v2ray-plugin",
+			yaml: `{name: "🇯🇵 日本 02", type: ss, server: jp02.example.net, port: "443", cipher: aes-128-gcm, password: s3cret, udp-over-tcp: true, plugin: v2ray-plugin, plugin-opts: {mode: websocket, tls: true, host: cdn.example.net, path: /ws}}`,
+			want: map[string]any{
+				"type": "shadowsocks", "tag": "🇯🇵 日本 02", "server": "jp02.example.net", "server_port": 443,
+				"method": "aes-128-gcm", "password": "s3cret", "udp_over_tcp": true,
+				"plugin": "v2ray-plugin", "plugin_opts": "mode=websocket;tls;host=cdn.example.net;path=/ws",
+			},
+		},
+		{
+			name: "vmess ws tls",
+			yaml: `{name: "🇺🇸 美国 03", type: vmess, server: us03.example.net, port: 443, uuid: 3f1c6a57-3c5e-4b8e-9d1a-2b7f0e6c9a11, alterId: 0, cipher: auto, udp: true, tls: true, servername: v.example.net, network: ws, ws-opts: {path: /vmess, headers: {Host: v.example.net}, max-early-data: 2048, early-data-header-name: Sec-WebSocket-Protocol}}`,
+			want: map[string]any{
+				"type": "vmess", "tag": "🇺🇸 美国 03", "server": "us03.example.net", "server_port": 443,
+				"uuid": "3f1c6a57-3c5e-4b8e-9d1a-2b7f0e6c9a11", "alter_id": 0, "security": "auto",
+				"tls": map[string]any{"enabled": true, "server_name": "v.example.net"},
+				"transport": map[string]any{
+					"type": "ws", "path": "/vmess", "headers": map[string]any{"Host": "v.example.net"},
+					"max_early_data": 2048, "early_data_header_name": "Sec-WebSocket-Protocol",
+				},
+			},
+		},
+		{
+			name: "vless reality vision",
+			yaml: `{name: "🇸🇬 新加坡 04", type: vless, server: 203.0.113.4, port: 443, uuid: 9b2e4d1c-7a6f-4e3b-8c5d-1f0a2b3c4d5e, network: tcp, tls: true, udp: true, flow: xtls-rprx-vision, servername: www.microsoft.com, reality-opts: {public-key: Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw, short-id: "6ba85179e30d4fc2"}, client-fingerprint: chrome}`,
+			want: map[string]any{
+				"type": "vless", "tag": "🇸🇬 新加坡 04", "server": "203.0.113.4", "server_port": 443,
+				"uuid": "9b2e4d1c-7a6f-4e3b-8c5d-1f0a2b3c4d5e", "flow": "xtls-rprx-vision",
+				"tls": map[string]any{
+					"enabled": true, "server_name": "www.microsoft.com",
+					"utls":    map[string]any{"enabled": true, "fingerprint": "chrome"},
+					"reality": map[string]any{"enabled": true, "public_key": "Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw", "short_id": "6ba85179e30d4fc2"},
+				},
+			},
+		},
+		{
+			name: "trojan grpc",
+			yaml: `{name: "🇹🇼 台湾 05", type: trojan, server: tw05.example.net, port: 443, password: 0c8a4e2f-trojan, udp: true, sni: t.example.net, skip-cert-verify: true, alpn: [h2], network: grpc, grpc-opts: {grpc-service-name: trojan-grpc}}`,
+			want: map[string]any{
+				"type": "trojan", "tag": "🇹🇼 台湾 05", "server": "tw05.example.net", "server_port": 443,
+				"password":  "0c8a4e2f-trojan",
+				"tls":       map[string]any{"enabled": true, "server_name": "t.example.net", "insecure": true, "alpn": []string{"h2"}},
+				"transport": map[string]any{"type": "grpc", "service_name": "trojan-grpc"},
+			},
+		},
+		{
+			name: "hysteria2 salamander",
+			yaml: `{name: "🇰🇷 韩国 06", type: hysteria2, server: kr06.example.net, port: 8443, password: hy2-pass, up: "30 Mbps", down: "200 Mbps", obfs: salamander, obfs-password: obfs-pass, sni: hy.example.net, skip-cert-verify: false}`,
+			want: map[string]any{
+				"type": "hysteria2", "tag": "🇰🇷 韩国 06", "server": "kr06.example.net", "server_port": 8443,
+				"password": "hy2-pass", "up_mbps": 30, "down_mbps": 200,
+				"obfs": map[string]any{"type": "salamander", "password": "obfs-pass"},
+				"tls":  map[string]any{"enabled": true, "server_name": "hy.example.net"},
+			},
+		},
+		{
+			name: "tuic v5",
+			yaml: `{name: "🇩🇪 德国 07", type: tuic, server: de07.example.net, port: 10443, uuid: 5c1e3a7b-2d4f-4a6b-9c8d-0e1f2a3b4c5d, password: tuic-pass, alpn: [h3], congestion-controller: bbr, udp-relay-mode: native, reduce-rtt: true, heartbeat-interval: 10000, sni: tuic.example.net}`,
+			want: map[string]any{
+				"type": "tuic", "tag": "🇩🇪 德国 07", "server": "de07.example.net", "server_port": 10443,
+				"uuid": "5c1e3a7b-2d4f-4a6b-9c8d-0e1f2a3b4c5d", "password": "tuic-pass",
+				"congestion_control": "bbr", "udp_relay_mode": "native", "zero_rtt_handshake": true, "heartbeat": "10000ms",
+				"tls": map[string]any{"enabled": true, "server_name": "tuic.example.net", "alpn": []string{"h3"}},
+			},
+		},
+		{name: "ssr unsupported", yaml: `{name: ssr, type: ssr, server: a.example.net, port: 443, cipher: aes-256-cfb, password: x, protocol: origin, obfs: plain}`, skip: `不支持的类型 "ssr"`},
+		{name: "tuic v4 token", yaml: `{name: v4, type: tuic, server: a.example.net, port: 443, token: abc}`, skip: "TUIC v4"},
+		{name: "vless without uuid", yaml: `{name: bad, type: vless, server: a.example.net, port: 443}`, skip: "缺少 uuid"},
+		{name: "missing port", yaml: `{name: bad, type: trojan, server: a.example.net, password: x}`, skip: "缺少 server 或 port"},
+		{name: "v2ray-plugin quic", yaml: `{name: bad, type: ss, server: a.example.net, port: 443, cipher: aes-128-gcm, password: x, plugin: v2ray-plugin, plugin-opts: {mode: quic}}`, skip: "mode=quic"},
+		{name: "unsupported transport", yaml: `{name: bad, type: vmess, server: a.example.net, port: 443, uuid: u, network: kcp}`, skip: `不支持的传输 "kcp"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxies, ok := clashProxies([]byte("proxies:\n  - " + tt.yaml + "\n"))
+			if !ok || len(proxies) != 1 {
+				t.Fatalf("clashProxies() = %v, %v", proxies, ok)
+			}
+			out, skipped := convertClashProxies(proxies)
+			if tt.skip != "" {
+				if len(out) != 0 || len(skipped) != 1 || !strings.Contains(skipped[0].Reason, tt.skip) {
+					t.Fatalf("got out=%v skipped=%+v, want skip with %q", out, skipped, tt.skip)
+				}
+				return
+			}
+			if len(skipped) != 0 || len(out) != 1 {
+				t.Fatalf("got out=%v skipped=%+v, want one outbound", out, skipped)
+			}
+			if !reflect.DeepEqual(out[0], tt.want) {
+				t.Errorf("outbound mismatch\n got: %#v\nwant: %#v", out[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClashSubscription(t *testing.T) {
+	doc := "port: 7890\nmode: rule\nproxies:\n" +
+		"  - {name: a, type: trojan, server: a.example.net, port: 443, password: x}\n" +
+		"  - {type: ssr, server: b.example.net, port: 443}\n" +
+		"proxy-groups: []\n"
+	tests := []struct {
+		name    string
+		content string
+		ok      bool
+		tags    []string
+		wantErr bool
+	}{
+		{name: "plain yaml", content: doc, ok: true, tags: []string{"a"}},
+		{name: "base64 yaml", content: base64.StdEncoding.EncodeToString([]byte(doc)), ok: true, tags: []string{"a"}},
+		{name: "no proxies key", content: "port: 7890\nmode: rule\n"},
+		{name: "share links", content: "trojan://x@a.example.net:443#a\n"},
+		{name: "nothing convertible", content: "proxies:\n  - {name: b, type: ssr, server: b.example.net, port: 443}\n", ok: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, ok, err := parseClashSubscription([]byte(tt.content))
+			if ok != tt.ok || (err != nil) != tt.wantErr {
+				t.Fatalf("parseClashSubscription() ok=%v err=%v, want ok=%v wantErr=%v", ok, err, tt.ok, tt.wantErr)
+			}
+			var tags []string
+			for _, ob := range out {
+				tags = append(tags, ob["tag"].(string))
+			}
+			if !reflect.DeepEqual(tags, tt.tags) {
+				t.Errorf("tags = %v, want %v", tags, tt.tags)
+			}
+		})
+	}
+}
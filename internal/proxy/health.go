@@ -0,0 +1,191 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// probeConcurrency 限制同时进行的探测数量。
+const probeConcurrency = 16
+
+// latencyWeight 是新探测结果在平均延迟中的权重。
+const latencyWeight = 0.3
+
+// Health 是节点的健康检查记录。
+type Health struct {
+	Reachable  bool
+	Latency    time.Duration // 最近一次成功探测的耗时
+	AvgLatency time.Duration // 成功探测耗时的指数加权平均
+	Successes  int
+	Failures   int
+	LastError  string
+	CheckedAt  time.Time
+}
+
+// Score 越小越好：平均延迟按失败比例放大。
+func (h Health) Score() float64 {
+	score := float64(h.AvgLatency.Milliseconds())
+	if total := h.Successes + h.Failures; total > 0 {
+		score *= 1 + float64(h.Failures)/float64(total)
+	}
+	return score
+}
+
+// healthTable 按节点 tag 保存健康检查结果。
+type healthTable struct {
+	mu    sync.Mutex
+	byTag map[string]*Health
+}
+
+func newHealthTable() *healthTable {
+	return &healthTable{byTag: map[string]*Health{}}
+}
+
+func (t *healthTable) record(tag string, latency time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.byTag[tag]
+	if h == nil {
+		h = &Health{}
+		t.byTag[tag] = h
+	}
+	h.CheckedAt = time.Now()
+	if err != nil {
+		h.Reachable = false
+		h.Failures++
+		h.LastError = err.Error()
+		return
+	}
+	h.Reachable = true
+	h.Successes++
+	h.LastError = ""
+	h.Latency = latency
+	if h.AvgLatency == 0 {
+		h.AvgLatency = latency
+	} else {
+		h.AvgLatency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(h.AvgLatency))
+	}
+}
+
+func (t *healthTable) get(tag string) (Health, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if h, ok := t.byTag[tag]; ok {
+		return *h, true
+	}
+	return Health{}, false
+}
+
+// prune 删除不在 endpoints 中的节点记录。
+func (t *healthTable) prune(endpoints []Endpoint) {
+	keep := make(map[string]bool, len(endpoints))
+	for _, ep := range endpoints {
+		keep[ep.Tag] = true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for tag := range t.byTag {
+		if !keep[tag] {
+			delete(t.byTag, tag)
+		}
+	}
+}
+
+// rank 去掉最近一次探测失败的节点，其余按得分排序；尚未探测的节点排在已探测节点之后。
+func (t *healthTable) rank(endpoints []Endpoint) []Endpoint {
+	type scored struct {
+		ep     Endpoint
+		probed bool
+		score  float64
+	}
+	t.mu.Lock()
+	list := make([]scored, 0, len(endpoints))
+	for _, ep := range endpoints {
+		h, ok := t.byTag[ep.Tag]
+		if ok && !h.Reachable {
+			continue
+		}
+		s := scored{ep: ep, probed: ok}
+		if ok {
+			s.score = h.Score()
+		}
+		list = append(list, s)
+	}
+	t.mu.Unlock()
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].probed != list[j].probed {
+			return list[i].probed
+		}
+		return list[i].score < list[j].score
+	})
+	out := make([]Endpoint, len(list))
+	for i, s := range list {
+		out[i] = s.ep
+	}
+	return out
+}
+
+// probeAll 并发探测所有节点并记录结果。
+func (t *healthTable) probeAll(ctx context.Context, endpoints []Endpoint) {
+	t.prune(endpoints)
+	if len(endpoints) == 0 {
+		return
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, probeConcurrency)
+	for _, ep := range endpoints {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(ep Endpoint) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			latency, err := probeEndpoint(ctx, ep)
+			if ctx.Err() != nil {
+				return
+			}
+			t.record(ep.Tag, latency, err)
+		}(ep)
+	}
+	wg.Wait()
+
+	alive := len(t.rank(endpoints))
+	fmt.Printf("🩺 节点健康检查完成：%d/%d 可用\n", alive, len(endpoints))
+}
+
+// probeEndpoint 经节点的本地 socks5 入站请求 probeURL，返回耗时；5xx 视为失败。
+func probeEndpoint(ctx context.Context, ep Endpoint) (time.Duration, error) {
+	proxyURL, err := url.Parse(ep.URL)
+	if err != nil {
+		return 0, err
+	}
+	transport := &http.Transport{Proxy: http.ProxyURL(proxyURL), DisableKeepAlives: true}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport, Timeout: probeTimeout}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	started := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return time.Since(started), nil
+}
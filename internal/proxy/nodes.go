@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ErrUnknownNode 表示当前 sing-box 配置中没有该节点。
+var ErrUnknownNode = errors.New("节点不存在")
+
+// subPrefix 匹配 normalizeOutbounds 为每个订阅添加的 tag 前缀。
+var subPrefix = regexp.MustCompile(`^sub(\d+)-`)
+
+// Node 汇总一个节点的来源、本地端口、冻结状态与统计。
+type Node struct {
+	Tag          string      `json:"tag"`
+	Type         string      `json:"type,omitempty"`
+	Source       string      `json:"source,omitempty"`       // 订阅序号，例如 sub2
+	Subscription string      `json:"subscription,omitempty"` // 来自已保存订阅时为其 URL；环境变量中的订阅不回传
+	Port         int         `json:"port"`
+	Frozen       bool        `json:"frozen"`
+	FrozenUntil  *time.Time  `json:"frozenUntil,omitempty"`
+	LastOutcome  string      `json:"lastOutcome,omitempty"`
+	Streak       int         `json:"streak"`
+	Successes    int         `json:"successes"`
+	Failures     int         `json:"failures"`
+	Health       *NodeHealth `json:"health,omitempty"`
+}
+
+// NodeHealth 是 Health 的 JSON 形式，延迟以毫秒计。
+type NodeHealth struct {
+	Reachable      bool      `json:"reachable"`
+	LatencyMs      int64     `json:"latencyMs"`
+	AvgLatencyMs   int64     `json:"avgLatencyMs"`
+	ProbeSuccesses int       `json:"probeSuccesses"`
+	ProbeFailures  int       `json:"probeFailures"`
+	LastError      string    `json:"lastError,omitempty"`
+	CheckedAt      time.Time `json:"checkedAt"`
+}
+
+// Nodes 返回当前 sing-box 配置中的全部节点（包括冻结与探测失败的），不等待首次启动完成。
+func (s *Supervisor) Nodes() ([]Node, error) {
+	s.mu.Lock()
+	endpoints := append([]Endpoint(nil), s.endpoints...)
+	s.mu.Unlock()
+	penalties, err := Penalties()
+	if err != nil {
+		return nil, err
+	}
+
+	urls := MergeEnvAndSaved(os.Getenv(singboxSubEnv))
+	stored := LoadStoredSubs()
+	now := time.Now()
+	nodes := make([]Node, 0, len(endpoints))
+	for _, ep := range endpoints {
+		n := Node{Tag: ep.Tag, Type: ep.Type, Port: extractPort(ep.URL)}
+		if m := subPrefix.FindStringSubmatch(ep.Tag); m != nil {
+			n.Source = "sub" + m[1]
+			if idx, _ := strconv.Atoi(m[1]); idx >= 1 && idx <= len(urls) && slices.Contains(stored, urls[idx-1]) {
+				n.Subscription = urls[idx-1]
+			}
+		}
+		if p, ok := penalties[ep.Tag]; ok {
+			n.Frozen = p.Frozen(now)
+			if n.Frozen {
+				until := p.Until
+				n.FrozenUntil = &until
+			}
+			n.LastOutcome = p.LastOutcome
+			n.Streak = p.Streak
+			n.Successes = p.Successes
+			n.Failures = p.Failures
+		}
+		if h, ok := s.health.get(ep.Tag); ok {
+			n.Health = &NodeHealth{
+				Reachable:      h.Reachable,
+				LatencyMs:      h.Latency.Milliseconds(),
+				AvgLatencyMs:   h.AvgLatency.Milliseconds(),
+				ProbeSuccesses: h.Successes,
+				ProbeFailures:  h.Failures,
+				LastError:      h.LastError,
+				CheckedAt:      h.CheckedAt,
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// Node 返回单个节点的信息，不存在时返回 ErrUnknownNode。
+func (s *Supervisor) Node(tag string) (Node, error) {
+	nodes, err := s.Nodes()
+	if err != nil {
+		return Node{}, err
+	}
+	for _, n := range nodes {
+		if n.Tag == tag {
+			return n, nil
+		}
+	}
+	return Node{}, ErrUnknownNode
+}
+
+// Freeze 手动冻结节点 d（<= 0 时使用 freezeDuration），返回更新后的节点信息。
+func (s *Supervisor) Freeze(tag string, d time.Duration) (Node, error) {
+	if _, err := s.Node(tag); err != nil {
+		return Node{}, err
+	}
+	if d <= 0 {
+		d = freezeDuration
+	}
+	if err := FreezeEndpointFor(tag, d); err != nil {
+		return Node{}, err
+	}
+	return s.Node(tag)
+}
+
+// Unfreeze 手动解除节点冻结，返回更新后的节点信息。
+func (s *Supervisor) Unfreeze(tag string) (Node, error) {
+	if _, err := s.Node(tag); err != nil {
+		return Node{}, err
+	}
+	if err := UnfreezeEndpoint(tag); err != nil {
+		return Node{}, err
+	}
+	return s.Node(tag)
+}
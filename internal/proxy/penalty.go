@@ -0,0 +1,267 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vertex-nano-banana-unlimited/internal/outcome"
+)
+
+const (
+	penaltyFile       = "tmp/singbox_penalty.json"
+	legacyPenaltyFile = "tmp/singbox_penalty.txt" // 旧格式：每行 tag,unix
+)
+
+// 结果类别，与场景结果代码一致；OutcomeError 表示导航、上传等步骤失败，OutcomeManual 为手动冻结/解冻。
+const (
+	OutcomeDownloaded = string(outcome.Downloaded)
+	OutcomeError      = string(outcome.Error)
+	OutcomeManual     = "manual"
+)
+
+// nodeFailure 报告结果是否计入连续失败（按指数退避延长冻结）：与节点有关的场景结果以及步骤失败。
+// 成功、内容拦截等其余结果不计入。
+func nodeFailure(o string) bool {
+	return outcome.Outcome(o).NodeFailure()
+}
+
+var penaltyMu sync.Mutex
+
+// 以下参数由 Configure 设置。
+var (
+	penaltyDurations  = map[string]time.Duration{}
+	penaltyMaxBackoff = 4 * time.Hour
+)
+
+// Penalty 是节点的冻结记录，保存在 tmp/singbox_penalty.json。
+type Penalty struct {
+	Until       time.Time `json:"until"`
+	LastOutcome string    `json:"lastOutcome,omitempty"`
+	Streak      int       `json:"streak,omitempty"` // 连续失败次数
+	Successes   int       `json:"successes,omitempty"`
+	Failures    int       `json:"failures,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Frozen 判断记录在 now 时是否处于冻结期。
+func (p Penalty) Frozen(now time.Time) bool {
+	return now.Before(p.Until)
+}
+
+// penaltyFor 返回 outcome 在已连续失败 streak 次（含本次）时的冻结时长。
+func penaltyFor(outcome string, streak int) time.Duration {
+	d, ok := penaltyDurations[outcome]
+	if !ok {
+		d = freezeDuration
+	}
+	for i := 1; i < streak && d < penaltyMaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, penaltyMaxBackoff)
+}
+
+// Penalize 按场景结果冻结节点并返回冻结时长：成功清零连续失败计数并使用较短的冷却，
+// 失败类结果连续出现时冻结时长逐次翻倍。
+func Penalize(tag, outcome string) (time.Duration, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return 0, nil
+	}
+	penaltyMu.Lock()
+	defer penaltyMu.Unlock()
+	penalties, err := readPenalties()
+	if err != nil {
+		return 0, err
+	}
+	p := penalties[tag]
+	// 只有失败类结果按连续失败次数退避，其余结果使用基础时长
+	streak := 1
+	switch {
+	case outcome == OutcomeDownloaded:
+		p.Streak = 0
+		p.Successes++
+	case nodeFailure(outcome):
+		p.Streak++
+		p.Failures++
+		streak = p.Streak
+	}
+	now := time.Now()
+	d := penaltyFor(outcome, streak)
+	if until := now.Add(d); until.After(p.Until) || outcome == OutcomeDownloaded {
+		p.Until = until
+	}
+	p.LastOutcome = outcome
+	p.UpdatedAt = now
+	penalties[tag] = p
+	if err := writePenalties(penalties); err != nil {
+		return 0, err
+	}
+	if d > 0 {
+		fmt.Printf("⏳ 节点 %s 冻结 %s（%s，连续失败 %d）\n", tag, d, outcome, p.Streak)
+	}
+	return d, nil
+}
+
+// FreezeEndpoint 按未分类结果冻结节点 freezeDuration。
+func FreezeEndpoint(tag string) error {
+	return FreezeEndpointFor(tag, freezeDuration)
+}
+
+// FreezeEndpointFor 手动冻结节点 d，不影响连续失败计数；d <= 0 时不做任何事。
+func FreezeEndpointFor(tag string, d time.Duration) error {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || d <= 0 {
+		return nil
+	}
+	penaltyMu.Lock()
+	defer penaltyMu.Unlock()
+	penalties, err := readPenalties()
+	if err != nil {
+		return err
+	}
+	p := penalties[tag]
+	p.Until = time.Now().Add(d)
+	p.LastOutcome = OutcomeManual
+	p.UpdatedAt = time.Now()
+	penalties[tag] = p
+	if err := writePenalties(penalties); err != nil {
+		return err
+	}
+	fmt.Printf("⏳ 节点 %s 冻结 %s\n", tag, d)
+	return nil
+}
+
+// UnfreezeEndpoint 立即解除节点冻结并清零连续失败计数，保留成功/失败统计。
+func UnfreezeEndpoint(tag string) error {
+	tag = strings.TrimSpace(tag)
+	penaltyMu.Lock()
+	defer penaltyMu.Unlock()
+	penalties, err := readPenalties()
+	if err != nil {
+		return err
+	}
+	p, ok := penalties[tag]
+	if !ok {
+		return nil
+	}
+	p.Until = time.Time{}
+	p.Streak = 0
+	p.LastOutcome = OutcomeManual
+	p.UpdatedAt = time.Now()
+	penalties[tag] = p
+	if err := writePenalties(penalties); err != nil {
+		return err
+	}
+	fmt.Printf("🔓 节点 %s 已解除冻结\n", tag)
+	return nil
+}
+
+// Penalties 返回全部节点的冻结记录。
+func Penalties() (map[string]Penalty, error) {
+	penaltyMu.Lock()
+	defer penaltyMu.Unlock()
+	return readPenalties()
+}
+
+// IsFrozen 判断节点当前是否处于冻结期。
+func IsFrozen(tag string) bool {
+	penaltyMu.Lock()
+	defer penaltyMu.Unlock()
+	penalties, _ := readPenalties()
+	p, ok := penalties[strings.TrimSpace(tag)]
+	return ok && p.Frozen(time.Now())
+}
+
+// FrozenSet 返回当前处于冻结期的节点集合，调用方可一次读取后逐个检查。
+func FrozenSet() map[string]bool {
+	penaltyMu.Lock()
+	penalties, _ := readPenalties()
+	penaltyMu.Unlock()
+	now := time.Now()
+	frozen := make(map[string]bool, len(penalties))
+	for tag, p := range penalties {
+		if p.Frozen(now) {
+			frozen[tag] = true
+		}
+	}
+	return frozen
+}
+
+func filterPenalized(endpoints []Endpoint) []Endpoint {
+	frozen := FrozenSet()
+	var out []Endpoint
+	for _, ep := range endpoints {
+		if frozen[ep.Tag] {
+			continue
+		}
+		out = append(out, ep)
+	}
+	return out
+}
+
+// readPenalties 读取 JSON 冻结记录；只有旧格式文件时迁移过来。调用方需持有 penaltyMu。
+func readPenalties() (map[string]Penalty, error) {
+	data, err := os.ReadFile(penaltyFile)
+	if os.IsNotExist(err) {
+		return migrateLegacyPenalties()
+	}
+	if err != nil {
+		return nil, err
+	}
+	penalties := map[string]Penalty{}
+	if len(data) == 0 {
+		return penalties, nil
+	}
+	if err := json.Unmarshal(data, &penalties); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", penaltyFile, err)
+	}
+	return penalties, nil
+}
+
+func writePenalties(penalties map[string]Penalty) error {
+	if err := os.MkdirAll(filepath.Dir(penaltyFile), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(penalties, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := penaltyFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, penaltyFile)
+}
+
+// migrateLegacyPenalties 把旧的 tag,unix 文本记录转换为 JSON 并删除旧文件。
+func migrateLegacyPenalties() (map[string]Penalty, error) {
+	penalties := map[string]Penalty{}
+	data, err := os.ReadFile(legacyPenaltyFile)
+	if os.IsNotExist(err) {
+		return penalties, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		tag, ts, ok := strings.Cut(strings.TrimSpace(line), ",")
+		if !ok {
+			continue
+		}
+		if sec, err := strconv.ParseInt(strings.TrimSpace(ts), 10, 64); err == nil {
+			penalties[strings.TrimSpace(tag)] = Penalty{Until: time.Unix(sec, 0), UpdatedAt: time.Now()}
+		}
+	}
+	if err := writePenalties(penalties); err != nil {
+		return nil, err
+	}
+	_ = os.Remove(legacyPenaltyFile)
+	fmt.Printf("🔁 已将 %s 迁移为 %s（%d 条）\n", legacyPenaltyFile, penaltyFile, len(penalties))
+	return penalties, nil
+}
@@ -0,0 +1,357 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// parseShareLinks 解析每行一个分享链接（ss://、vmess://、vless://、trojan://、hy2://）的订阅，
+// 内容可以是明文或 base64。链接先转换为 Clash 节点格式，再复用 Clash 转换逻辑生成 sing-box outbounds。
+// ok 为 false 表示内容不是分享链接列表。
+func parseShareLinks(content []byte) (out []map[string]any, ok bool, err error) {
+	text := string(content)
+	if !strings.Contains(text, "://") {
+		dec, derr := decodeBase64Loose(text)
+		if derr != nil || !strings.Contains(string(dec), "://") {
+			return nil, false, nil
+		}
+		text = string(dec)
+	}
+
+	var proxies []map[string]any
+	var skipped []clashSkip
+	total := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		scheme, _, found := strings.Cut(line, "://")
+		if !found {
+			continue
+		}
+		total++
+		p, err := shareLinkProxy(strings.ToLower(scheme), line)
+		if err != nil {
+			skipped = append(skipped, clashSkip{Name: shareLinkName(line), Type: scheme, Reason: err.Error()})
+			continue
+		}
+		if clashString(p, "name") == "" {
+			p["name"] = fmt.Sprintf("link-%d", total)
+		}
+		proxies = append(proxies, p)
+	}
+	if total == 0 {
+		return nil, false, nil
+	}
+
+	out, failed := convertClashProxies(proxies)
+	skipped = append(skipped, failed...)
+	for _, s := range skipped {
+		fmt.Printf("⏭️ 跳过分享链接 %s (%s): %s\n", s.Name, s.Type, s.Reason)
+	}
+	fmt.Printf("🧭 分享链接订阅：转换 %d 个节点，跳过 %d 个\n", len(out), len(skipped))
+	if len(out) == 0 {
+		return nil, true, fmt.Errorf("订阅中没有可转换的分享链接（共 %d 条）", total)
+	}
+	return out, true, nil
+}
+
+// shareLinkName 返回链接 # 之后的名称，用于日志。
+func shareLinkName(link string) string {
+	if _, frag, ok := strings.Cut(link, "#"); ok {
+		if name, err := url.PathUnescape(frag); err == nil {
+			return name
+		}
+		return frag
+	}
+	if len(link) > 32 {
+		return link[:32] + "…"
+	}
+	return link
+}
+
+// shareLinkProxy 把单个分享链接转换为 Clash 节点 map。
+func shareLinkProxy(scheme, link string) (map[string]any, error) {
+	switch scheme {
+	case "ss":
+		return ssLinkProxy(link)
+	case "vmess":
+		return vmessLinkProxy(link)
+	case "vless", "trojan":
+		u, err := url.Parse(link)
+		if err != nil {
+			return nil, err
+		}
+		p, err := linkBase(u, scheme)
+		if err != nil {
+			return nil, err
+		}
+		q := u.Query()
+		if scheme == "vless" {
+			p["uuid"] = u.User.Username()
+			if flow := q.Get("flow"); flow != "" {
+				p["flow"] = flow
+			}
+		} else {
+			p["password"] = u.User.Username()
+		}
+		linkTLS(p, q, scheme == "trojan")
+		linkTransport(p, q)
+		return p, nil
+	case "hy2", "hysteria2":
+		u, err := url.Parse(link)
+		if err != nil {
+			return nil, err
+		}
+		p, err := linkBase(u, "hysteria2")
+		if err != nil {
+			return nil, err
+		}
+		// 认证信息可能写成 user:pass，整体作为密码
+		p["password"] = u.User.String()
+		if pw, err := url.PathUnescape(u.User.String()); err == nil {
+			p["password"] = pw
+		}
+		q := u.Query()
+		if obfs := q.Get("obfs"); obfs != "" {
+			p["obfs"] = obfs
+			p["obfs-password"] = q.Get("obfs-password")
+		}
+		linkTLS(p, q, true)
+		return p, nil
+	default:
+		return nil, fmt.Errorf("不支持的链接类型 %s://", scheme)
+	}
+}
+
+// linkBase 读取 host、port 与 # 后的名称。
+func linkBase(u *url.URL, typ string) (map[string]any, error) {
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("缺少认证信息")
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("地址 %q 无效", u.Host)
+	}
+	return map[string]any{
+		"type":   typ,
+		"name":   u.Fragment,
+		"server": u.Hostname(),
+		"port":   port,
+	}, nil
+}
+
+// linkTLS 把 security、sni、fp、alpn、allowInsecure 与 reality 参数写成 Clash 字段。
+func linkTLS(p map[string]any, q url.Values, always bool) {
+	security := strings.ToLower(q.Get("security"))
+	if !always && security != "tls" && security != "reality" && security != "xtls" {
+		return
+	}
+	p["tls"] = true
+	if sni := q.Get("sni"); sni != "" {
+		p["servername"] = sni
+	} else if peer := q.Get("peer"); peer != "" {
+		p["servername"] = peer
+	}
+	if fp := q.Get("fp"); fp != "" {
+		p["client-fingerprint"] = fp
+	}
+	if alpn := q.Get("alpn"); alpn != "" {
+		p["alpn"] = toAnyList(splitList(alpn))
+	}
+	for _, key := range []string{"allowInsecure", "insecure"} {
+		if v := q.Get(key); v == "1" || strings.EqualFold(v, "true") {
+			p["skip-cert-verify"] = true
+		}
+	}
+	if security == "reality" {
+		p["reality-opts"] = map[string]any{"public-key": q.Get("pbk"), "short-id": q.Get("sid")}
+	}
+}
+
+// linkTransport 把 type、host、path、serviceName、headerType 参数写成 Clash 的 network 与 *-opts。
+func linkTransport(p map[string]any, q url.Values) {
+	host, path := q.Get("host"), q.Get("path")
+	switch network := strings.ToLower(q.Get("type")); network {
+	case "", "tcp":
+		if strings.EqualFold(q.Get("headerType"), "http") {
+			opts := map[string]any{"path": []any{strings.TrimSpace(path)}}
+			if host != "" {
+				opts["headers"] = map[string]any{"Host": toAnyList(splitList(host))}
+			}
+			p["network"] = "http"
+			p["http-opts"] = opts
+		}
+	case "ws":
+		opts := map[string]any{"path": path}
+		// path 中的 ?ed=2048 表示 WebSocket early data
+		if base, query, ok := strings.Cut(path, "?"); ok {
+			values, _ := url.ParseQuery(query)
+			if ed, err := strconv.Atoi(values.Get("ed")); err == nil && ed > 0 {
+				opts["path"] = base
+				opts["max-early-data"] = ed
+			}
+		}
+		if host != "" {
+			opts["headers"] = map[string]any{"Host": host}
+		}
+		p["network"] = "ws"
+		p["ws-opts"] = opts
+	case "grpc":
+		p["network"] = "grpc"
+		p["grpc-opts"] = map[string]any{"grpc-service-name": q.Get("serviceName")}
+	case "h2", "http":
+		opts := map[string]any{"path": path}
+		if host != "" {
+			opts["host"] = toAnyList(splitList(host))
+		}
+		p["network"] = "h2"
+		p["h2-opts"] = opts
+	default:
+		// 交给 clashTransport 报告不支持的传输
+		p["network"] = network
+	}
+}
+
+// ssLinkProxy 解析 SIP002（ss://base64(method:password)@host:port 或明文 method:password）
+// 以及旧格式 ss://base64(method:password@host:port)。
+func ssLinkProxy(link string) (map[string]any, error) {
+	body, fragment, _ := strings.Cut(link[len("ss://"):], "#")
+	legacy := !strings.Contains(body, "@")
+	if legacy {
+		encoded, query, _ := strings.Cut(body, "?")
+		dec, err := decodeBase64Loose(strings.TrimSuffix(encoded, "/"))
+		if err != nil {
+			return nil, errors.New("无法解码旧格式 ss 链接")
+		}
+		body = string(dec)
+		if query != "" {
+			body += "/?" + query
+		}
+	}
+	// 标准 base64 的 userinfo 可能含有 /，旧格式的明文密码可能含有 / @ ? 等字符，
+	// 直接交给 url.Parse 会把它们当成路径或主机，因此按最后一个 @ 手动拆分
+	at := strings.LastIndex(body, "@")
+	if at < 0 {
+		return nil, errors.New("缺少认证信息")
+	}
+	userinfo := body[:at]
+	rest := "ss://ss@" + body[at+1:]
+	if fragment != "" {
+		rest += "#" + fragment
+	}
+	u, err := url.Parse(rest)
+	if err != nil {
+		return nil, err
+	}
+	p, err := linkBase(u, "ss")
+	if err != nil {
+		return nil, err
+	}
+	if !legacy {
+		if s, err := url.PathUnescape(userinfo); err == nil {
+			userinfo = s
+		}
+	}
+	method, password, ok := strings.Cut(userinfo, ":")
+	if !ok {
+		// base64 字母表不含 :，没有 : 时按 base64(method:password) 解码
+		dec, err := decodeBase64Loose(userinfo)
+		if err != nil {
+			return nil, errors.New("无法解码 ss 认证信息")
+		}
+		method, password, _ = strings.Cut(string(dec), ":")
+	}
+	if method == "" || password == "" {
+		return nil, errors.New("缺少加密方式或密码")
+	}
+	p["cipher"], p["password"] = method, password
+	if plugin := u.Query().Get("plugin"); plugin != "" {
+		name, optStr, _ := strings.Cut(plugin, ";")
+		opts := map[string]any{}
+		for _, kv := range strings.Split(optStr, ";") {
+			k, v, _ := strings.Cut(kv, "=")
+			switch k {
+			case "":
+			case "obfs":
+				opts["mode"] = v
+			case "obfs-host":
+				opts["host"] = v
+			case "tls":
+				opts["tls"] = true
+			default:
+				opts[k] = v
+			}
+		}
+		switch name {
+		case "obfs-local", "simple-obfs":
+			name = "obfs"
+		}
+		p["plugin"], p["plugin-opts"] = name, opts
+	}
+	return p, nil
+}
+
+// vmessLinkProxy 解析 v2rayN 格式的 vmess://base64(JSON)。
+func vmessLinkProxy(link string) (map[string]any, error) {
+	dec, err := decodeBase64Loose(link[len("vmess://"):])
+	if err != nil {
+		return nil, errors.New("无法解码 vmess 链接")
+	}
+	var v map[string]any
+	if err := json.Unmarshal(dec, &v); err != nil {
+		return nil, fmt.Errorf("vmess JSON 无效: %v", err)
+	}
+	host := clashString(v, "host")
+	p := map[string]any{
+		"type":    "vmess",
+		"name":    clashString(v, "ps"),
+		"server":  clashString(v, "add"),
+		"port":    clashInt(v, "port"),
+		"uuid":    clashString(v, "id"),
+		"alterId": clashInt(v, "aid"),
+		"cipher":  clashStringOr(v, "scy", "auto"),
+	}
+	q := url.Values{}
+	for _, key := range []string{"sni", "fp", "alpn", "path"} {
+		q.Set(key, clashString(v, key))
+	}
+	q.Set("host", host)
+	if strings.EqualFold(clashString(v, "tls"), "tls") {
+		q.Set("security", "tls")
+		if q.Get("sni") == "" && host != "" {
+			q.Set("sni", strings.TrimSpace(strings.Split(host, ",")[0]))
+		}
+	}
+	network := clashString(v, "net")
+	q.Set("type", network)
+	if network == "grpc" {
+		q.Set("serviceName", clashString(v, "path"))
+	}
+	if network == "" || network == "tcp" {
+		q.Set("headerType", clashString(v, "type"))
+	}
+	linkTLS(p, q, false)
+	linkTransport(p, q)
+	return p, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func toAnyList(items []string) []any {
+	out := make([]any, len(items))
+	for i, s := range items {
+		out[i] = s
+	}
+	return out
+}
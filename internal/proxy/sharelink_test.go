@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// v2rayN 导出的 vmess 链接：ws + tls，path 带 early data 参数
+const vmessSample = `{"v":"2","ps":"🇺🇸 vmess","add":"vm.example.net","port":"443","id":"3f1c6a57-3c5e-4b8e-9d1a-2b7f0e6c9a11","aid":"0","scy":"auto","net":"ws","type":"none","host":"cdn.example.net","path":"/ray?ed=2048","tls":"tls","sni":"","alpn":"","fp":"chrome"}`
+
+func TestShareLinkOutbound(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want map[string]any
+		skip string // 非空时期望跳过，且原因包含该字符串
+	}{
+		{
+			name: "ss sip002 obfs plugin",
+			link: "ss://YWVzLTEyOC1nY206dGVzdA==@192.0.2.1:8388/?plugin=obfs-local%3Bobfs%3Dhttp%3Bobfs-host%3Dwww.bing.com#%E9%A6%99%E6%B8%AF%2001",
+			want: map[string]any{
+				"type": "shadowsocks", "tag": "香港 01", "server": "192.0.2.1", "server_port": 8388,
+				"method": "aes-128-gcm", "password": "test",
+				"plugin": "obfs-local", "plugin_opts": "obfs=http;obfs-host=www.bing.com",
+			},
+		},
+		{
+			name: "ss standard base64 userinfo with slash",
+			link: "ss://Y2hhY2hhMjAtaWV0Zi1wb2x5MTMwNTpSM2w/Pn5wWg==@192.0.2.3:8388#slash",
+			want: map[string]any{
+				"type": "shadowsocks", "tag": "slash", "server": "192.0.2.3", "server_port": 8388,
+				"method": "chacha20-ietf-poly1305", "password": "R3l?>~pZ",
+			},
+		},
+		{
+			name: "ss legacy password with @ and slash",
+			link: "ss://YWVzLTI1Ni1nY206cGFAc3Mvd29yZEAxOTguNTEuMTAwLjc6NDQz#legacy",
+			want: map[string]any{
+				"type": "shadowsocks", "tag": "legacy", "server": "198.51.100.7", "server_port": 443,
+				"method": "aes-256-gcm", "password": "pa@ss/word",
+			},
+		},
+		{
+			name: "ss 2022 plain userinfo",
+			link: "ss://2022-blake3-aes-128-gcm:YctPZ6U7xPPcU%2Bgp3u%2BkqA%3D%3D@192.0.2.2:443#ss2022",
+			want: map[string]any{
+				"type": "shadowsocks", "tag": "ss2022", "server": "192.0.2.2", "server_port": 443,
+				"method": "2022-blake3-aes-128-gcm", "password": "YctPZ6U7xPPcU+gp3u+kqA==",
+			},
+		},
+		{
+			name: "vmess ws tls early data",
+			link: "vmess://" + base64.StdEncoding.EncodeToString([]byte(vmessSample)),
+			want: map[string]any{
+				"type": "vmess", "tag": "🇺🇸 vmess", "server": "vm.example.net", "server_port": 443,
+				"uuid": "3f1c6a57-3c5e-4b8e-9d1a-2b7f0e6c9a11", "alter_id": 0, "security": "auto",
+				"tls": map[string]any{
+					"enabled": true, "server_name": "cdn.example.net",
+					"utls": map[string]any{"enabled": true, "fingerprint": "chrome"},
+				},
+				"transport": map[string]any{
+					"type": "ws", "path": "/ray", "headers": map[string]any{"Host": "cdn.example.net"},
+					"max_early_data": 2048, "early_data_header_name": "Sec-WebSocket-Protocol",
+				},
+			},
+		},
+		{
+			name: "vless reality vision",
+			link: "vless://9b2e4d1c-7a6f-4e3b-8c5d-1f0a2b3c4d5e@203.0.113.9:443?encryption=none&flow=xtls-rprx-vision&security=reality&sni=www.apple.com&fp=safari&pbk=Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw&sid=ab12&type=tcp&headerType=none#reality",
+			want: map[string]any{
+				"type": "vless", "tag": "reality", "server": "203.0.113.9", "server_port": 443,
+				"uuid": "9b2e4d1c-7a6f-4e3b-8c5d-1f0a2b3c4d5e", "flow": "xtls-rprx-vision",
+				"tls": map[string]any{
+					"enabled": true, "server_name": "www.apple.com",
+					"utls":    map[string]any{"enabled": true, "fingerprint": "safari"},
+					"reality": map[string]any{"enabled": true, "public_key": "Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw", "short_id": "ab12"},
+				},
+			},
+		},
+		{
+			name: "vless grpc tls",
+			link: "vless://5c1e3a7b-2d4f-4a6b-9c8d-0e1f2a3b4c5d@g.example.net:443?encryption=none&security=tls&sni=g.example.net&alpn=h2%2Chttp%2F1.1&type=grpc&serviceName=vl-grpc&mode=gun#grpc",
+			want: map[string]any{
+				"type": "vless", "tag": "grpc", "server": "g.example.net", "server_port": 443,
+				"uuid":      "5c1e3a7b-2d4f-4a6b-9c8d-0e1f2a3b4c5d",
+				"tls":       map[string]any{"enabled": true, "server_name": "g.example.net", "alpn": []string{"h2", "http/1.1"}},
+				"transport": map[string]any{"type": "grpc", "service_name": "vl-grpc"},
+			},
+		},
+		{
+			name: "trojan ws",
+			link: "trojan://pass%40word@t.example.net:443?security=tls&sni=t.example.net&type=ws&host=cdn.t.example.net&path=%2Ftrojan&allowInsecure=1#trojan",
+			want: map[string]any{
+				"type": "trojan", "tag": "trojan", "server": "t.example.net", "server_port": 443,
+				"password":  "pass@word",
+				"tls":       map[string]any{"enabled": true, "server_name": "t.example.net", "insecure": true},
+				"transport": map[string]any{"type": "ws", "path": "/trojan", "headers": map[string]any{"Host": "cdn.t.example.net"}},
+			},
+		},
+		{
+			name: "hy2 salamander",
+			link: "hy2://hy-pass@hy.example.net:443/?obfs=salamander&obfs-password=obfs-pass&sni=hy.example.net&insecure=1#hy2",
+			want: map[string]any{
+				"type": "hysteria2", "tag": "hy2", "server": "hy.example.net", "server_port": 443,
+				"password": "hy-pass",
+				"obfs":     map[string]any{"type": "salamander", "password": "obfs-pass"},
+				"tls":      map[string]any{"enabled": true, "server_name": "hy.example.net", "insecure": true},
+			},
+		},
+		{name: "ssr unsupported", link: "ssr://MTI3LjAuMC4xOjQ0MzpvcmlnaW46YWVzLTI1Ni1jZmI6cGxhaW46ZEdWemRB", skip: "不支持的链接类型"},
+		{name: "ss legacy not base64", link: "ss://!!!#bad", skip: "无法解码旧格式"},
+		{name: "ss userinfo not base64", link: "ss://!!!@192.0.2.1:443#bad", skip: "无法解码 ss 认证信息"},
+		{name: "ss missing port", link: "ss://YWVzLTEyOC1nY206dGVzdA==@192.0.2.1#bad", skip: "无效"},
+		{name: "vless missing uuid", link: "vless://@203.0.113.9:443?security=tls#bad", skip: "缺少认证信息"},
+		{name: "vmess not json", link: "vmess://" + base64.StdEncoding.EncodeToString([]byte("not json")), skip: "vmess JSON 无效"},
+		{name: "vless unsupported transport", link: "vless://u@203.0.113.9:443?type=kcp#bad", skip: `不支持的传输 "kcp"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, ok, err := parseShareLinks([]byte(tt.link + "\n"))
+			if !ok {
+				t.Fatalf("parseShareLinks() ok=false")
+			}
+			if tt.skip != "" {
+				if err == nil {
+					t.Fatalf("got %v, want link skipped", out)
+				}
+				scheme, _, _ := strings.Cut(tt.link, "://")
+				var reason string
+				if p, perr := shareLinkProxy(scheme, tt.link); perr != nil {
+					reason = perr.Error()
+				} else if _, skipped := convertClashProxies([]map[string]any{p}); len(skipped) == 1 {
+					reason = skipped[0].Reason
+				}
+				if !strings.Contains(reason, tt.skip) {
+					t.Errorf("skip reason %q does not mention %q", reason, tt.skip)
+				}
+				return
+			}
+			if err != nil || len(out) != 1 {
+				t.Fatalf("parseShareLinks() = %v, %v, want one outbound", out, err)
+			}
+			if !reflect.DeepEqual(out[0], tt.want) {
+				t.Errorf("outbound mismatch\n got: %#v\nwant: %#v", out[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestParseShareLinks(t *testing.T) {
+	list := strings.Join([]string{
+		"ss://YWVzLTEyOC1nY206dGVzdA==@192.0.2.1:8388#ss",
+		"",
+		"ssr://MTI3LjAuMC4xOjQ0Mw",
+		"trojan://pw@t.example.net:443",
+		"hy2://hy-pass@hy.example.net:443#hy2",
+	}, "\r\n")
+	tests := []struct {
+		name    string
+		content string
+		ok      bool
+		tags    []string
+		wantErr bool
+	}{
+		{name: "plain list", content: list, ok: true, tags: []string{"ss", "link-3", "hy2"}},
+		{name: "base64 list", content: base64.StdEncoding.EncodeToString([]byte(list)), ok: true, tags: []string{"ss", "link-3", "hy2"}},
+		{name: "wrapped base64 list", content: wrapLines(base64.StdEncoding.EncodeToString([]byte(list)), 76), ok: true, tags: []string{"ss", "link-3", "hy2"}},
+		{name: "not links", content: "port: 7890\n"},
+		{name: "nothing convertible", content: "ssr://MTI3LjAuMC4xOjQ0Mw\n", ok: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, ok, err := parseShareLinks([]byte(tt.content))
+			if ok != tt.ok || (err != nil) != tt.wantErr {
+				t.Fatalf("parseShareLinks() ok=%v err=%v, want ok=%v wantErr=%v", ok, err, tt.ok, tt.wantErr)
+			}
+			var tags []string
+			for _, ob := range out {
+				tags = append(tags, ob["tag"].(string))
+			}
+			if !reflect.DeepEqual(tags, tt.tags) {
+				t.Errorf("tags = %v, want %v", tags, tt.tags)
+			}
+		})
+	}
+}
+
+// wrapLines 按 width 折行，模拟部分订阅返回的带换行 base64。
+func wrapLines(s string, width int) string {
+	var b strings.Builder
+	for len(s) > width {
+		b.WriteString(s[:width] + "\n")
+		s = s[width:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
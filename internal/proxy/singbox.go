@@ -14,14 +14,13 @@ import (
 	"net"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
-	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
+
+	"vertex-nano-banana-unlimited/internal/config"
 )
 
 const (
@@ -29,61 +28,61 @@ const (
 	singboxSubEnv     = "PROXY_SINGBOX_SUB_URLS"
 	singboxCacheFile  = "tmp/singbox/outbounds.json"
 	singboxConfigFile = "tmp/singbox/config.json"
-	singboxPenalty    = "tmp/singbox_penalty.txt"
 	singboxBinName    = "sing-box"
-	singboxVersion    = "1.10.6"
-	singboxBasePort   = 17880
 )
 
-var penaltyMu sync.Mutex
+// 以下参数可通过 Configure 修改，默认值与 config.Default() 一致。
+var (
+	singboxVersion  = "1.10.6"
+	singboxBasePort = 17880
+	freezeDuration  = 15 * time.Minute
+	probeURL        = "https://www.gstatic.com/generate_204"
+	probeInterval   = 2 * time.Minute
+	probeTimeout    = 10 * time.Second
+)
+
+// Configure 应用代理相关配置，需在启动 sing-box 之前调用。
+func Configure(cfg config.ProxyConfig) {
+	singboxVersion = cfg.SingboxVersion
+	singboxBasePort = cfg.SingboxBasePort
+	freezeDuration = cfg.FreezeDuration
+	probeURL = cfg.ProbeURL
+	probeInterval = cfg.ProbeInterval
+	probeTimeout = cfg.ProbeTimeout
+	penaltyDurations = cfg.Penalties
+	penaltyMaxBackoff = cfg.PenaltyMaxBackoff
+}
 
-// StartSingBox 启动 sing-box，多订阅合并缓存，按节点生成独立端口并返回可用代理列表。
-// 如未配置订阅，返回空列表并不报错。
-func StartSingBox(ctx context.Context) ([]Endpoint, func(), error) {
+// prepareSingBox 合并订阅、生成配置文件并确保二进制存在，返回二进制路径与按节点分配端口的代理列表。
+// 未配置订阅时返回空列表且不报错。
+func prepareSingBox(ctx context.Context) (string, []Endpoint, error) {
 	urls := MergeEnvAndSaved(os.Getenv(singboxSubEnv))
 	if len(urls) == 0 {
-		return nil, func() {}, nil
+		return "", nil, nil
 	}
 
 	if err := os.MkdirAll(singboxDir, 0o755); err != nil {
-		return nil, func() {}, fmt.Errorf("make sing-box dir: %w", err)
+		return "", nil, fmt.Errorf("make sing-box dir: %w", err)
 	}
 
 	outbounds, err := loadOrFetchOutbounds(ctx, urls)
 	if err != nil {
-		return nil, func() {}, fmt.Errorf("load subscriptions: %w", err)
+		return "", nil, fmt.Errorf("load subscriptions: %w", err)
 	}
 
 	cfg, endpoints := buildConfig(outbounds)
 	if len(endpoints) == 0 {
-		return nil, func() {}, errors.New("订阅未提供可用节点(outbounds)")
+		return "", nil, errors.New("订阅未提供可用节点(outbounds)")
 	}
 	if err := writeJSONFile(singboxConfigFile, cfg); err != nil {
-		return nil, func() {}, fmt.Errorf("write config: %w", err)
+		return "", nil, fmt.Errorf("write config: %w", err)
 	}
 
 	bin, err := ensureSingBoxBinary(ctx)
 	if err != nil {
-		return nil, func() {}, fmt.Errorf("ensure binary: %w", err)
-	}
-
-	cmd := exec.CommandContext(ctx, bin, "run", "-c", singboxConfigFile, "--disable-color")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Start(); err != nil {
-		return nil, func() {}, fmt.Errorf("start sing-box: %w", err)
-	}
-
-	stop := func() {
-		_ = cmd.Process.Kill()
-	}
-
-	if len(endpoints) > 0 {
-		firstPort := extractPort(endpoints[0].URL)
-		_ = waitPortReady(ctx, "127.0.0.1", firstPort, 10*time.Second)
+		return "", nil, fmt.Errorf("ensure binary: %w", err)
 	}
-
-	return filterPenalized(endpoints), stop, nil
+	return bin, endpoints, nil
 }
 
 // WarmupSingBox 预先拉取订阅并下载二进制，但不启动进程。
@@ -102,21 +101,6 @@ func WarmupSingBox(ctx context.Context) error {
 	return err
 }
 
-// FreezeEndpoint 在成功或失败后冻结该节点 15 分钟。
-func FreezeEndpoint(tag string) error {
-	tag = strings.TrimSpace(tag)
-	if tag == "" {
-		return nil
-	}
-	penaltyMu.Lock()
-	defer penaltyMu.Unlock()
-	if err := savePenalty(singboxPenalty, tag, 15*time.Minute); err != nil {
-		return err
-	}
-	fmt.Printf("⏳ 节点 %s 冻结 15 分钟\n", tag)
-	return nil
-}
-
 func loadOrFetchOutbounds(ctx context.Context, urls []string) ([]map[string]any, error) {
 	if data, err := os.ReadFile(singboxCacheFile); err == nil {
 		var out []map[string]any
@@ -167,12 +151,25 @@ func fetchSubscription(ctx context.Context, url string) ([]map[string]any, error
 	if len(content) == 0 {
 		return nil, errors.New("订阅响应为空")
 	}
+	return decodeSubscription(content)
+}
+
+// decodeSubscription 识别订阅格式：sing-box JSON（可经 base64 包装）或 Clash/Mihomo YAML。
+func decodeSubscription(content []byte) ([]map[string]any, error) {
 	jsonBytes := content
 	if !json.Valid(content) {
 		if dec, err := base64.StdEncoding.DecodeString(string(content)); err == nil && json.Valid(dec) {
 			jsonBytes = dec
 		}
 	}
+	if !json.Valid(jsonBytes) {
+		if out, ok, err := parseClashSubscription(content); ok {
+			return out, err
+		}
+		if out, ok, err := parseShareLinks(content); ok {
+			return out, err
+		}
+	}
 	var cfg map[string]any
 	if err := json.Unmarshal(jsonBytes, &cfg); err != nil {
 		return nil, fmt.Errorf("解析订阅 JSON 失败: %w", err)
@@ -262,7 +259,8 @@ func buildConfig(outbounds []map[string]any) (map[string]any, []Endpoint) {
 			"inbound":  []string{inTag},
 			"outbound": tag,
 		})
-		endpoints = append(endpoints, Endpoint{Tag: tag, URL: fmt.Sprintf("socks5://127.0.0.1:%d", port)})
+		obType, _ := ob["type"].(string)
+		endpoints = append(endpoints, Endpoint{Tag: tag, URL: fmt.Sprintf("socks5://127.0.0.1:%d", port), Type: obType})
 	}
 
 	outWithDefaults := append([]map[string]any{}, outbounds...)
@@ -305,72 +303,6 @@ func writeJSONFile(path string, v any) error {
 	return os.WriteFile(path, data, 0o644)
 }
 
-// ------------------ penalty helpers ------------------
-
-func savePenalty(path, tag string, dur time.Duration) error {
-	penalties, err := readPenaltiesFile(path)
-	if err != nil {
-		return err
-	}
-	penalties[tag] = time.Now().Add(dur)
-	return writePenaltiesFile(path, penalties)
-}
-
-func readPenaltiesFile(path string) (map[string]time.Time, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return map[string]time.Time{}, nil
-		}
-		return nil, err
-	}
-	penalties := make(map[string]time.Time)
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		parts := strings.SplitN(line, ",", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		if ts, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64); err == nil {
-			penalties[strings.TrimSpace(parts[0])] = time.Unix(ts, 0)
-		}
-	}
-	return penalties, nil
-}
-
-func writePenaltiesFile(path string, penalties map[string]time.Time) error {
-	if len(penalties) == 0 {
-		_ = os.Remove(path)
-		return nil
-	}
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return err
-	}
-	var lines []string
-	for k, v := range penalties {
-		lines = append(lines, fmt.Sprintf("%s,%d", k, v.Unix()))
-	}
-	sort.Strings(lines)
-	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644)
-}
-
-func filterPenalized(endpoints []Endpoint) []Endpoint {
-	penalties, _ := readPenaltiesFile(singboxPenalty)
-	now := time.Now()
-	var out []Endpoint
-	for _, ep := range endpoints {
-		if exp, ok := penalties[ep.Tag]; ok && now.Before(exp) {
-			continue
-		}
-		out = append(out, ep)
-	}
-	return out
-}
-
 func hasRealOutbounds(items []map[string]any) bool {
 	for _, ob := range items {
 		if t, _ := ob["type"].(string); isRealOutboundType(t) {
@@ -0,0 +1,234 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	supervisorMinBackoff = 2 * time.Second
+	supervisorMaxBackoff = time.Minute
+)
+
+// Supervisor 持有唯一的长期 sing-box 进程：崩溃后按退避重启，订阅变更时重新加载，
+// 定期探测各节点的连通性与延迟，并向所有运行提供按健康状况排序的节点列表。
+type Supervisor struct {
+	mu        sync.Mutex
+	endpoints []Endpoint
+	started   bool
+	cancel    context.CancelFunc
+	reload    chan struct{}
+	ready     chan struct{}
+	readyOnce sync.Once
+	done      chan struct{}
+	health    *healthTable
+	probeNow  chan struct{}
+	// generation 在 sing-box 进程每次因重新加载或崩溃停止时加一
+	generation uint64
+}
+
+func NewSupervisor() *Supervisor {
+	return &Supervisor{
+		reload:   make(chan struct{}, 1),
+		ready:    make(chan struct{}),
+		done:     make(chan struct{}),
+		health:   newHealthTable(),
+		probeNow: make(chan struct{}, 1),
+	}
+}
+
+// Start 在后台启动监督循环；重复调用无效果。ctx 结束或调用 Stop 时进程被终止。
+func (s *Supervisor) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	ctx, s.cancel = context.WithCancel(ctx)
+	go s.loop(ctx)
+	if probeInterval > 0 {
+		go s.probeLoop(ctx)
+	}
+}
+
+// Stop 终止 sing-box 并等待监督循环退出。
+func (s *Supervisor) Stop() {
+	s.mu.Lock()
+	cancel, started := s.cancel, s.started
+	s.mu.Unlock()
+	if !started {
+		return
+	}
+	cancel()
+	<-s.done
+}
+
+// Reload 要求重新拉取订阅并重启 sing-box（例如订阅列表被修改后）。
+func (s *Supervisor) Reload() {
+	select {
+	case s.reload <- struct{}{}:
+	default:
+	}
+}
+
+// Endpoints 返回当前可用（未冻结、最近一次探测未失败）的节点，按健康得分从好到差排序；
+// 首次启动尚未完成时等待其结果。
+func (s *Supervisor) Endpoints(ctx context.Context) []Endpoint {
+	select {
+	case <-s.ready:
+	case <-ctx.Done():
+		return nil
+	}
+	s.mu.Lock()
+	endpoints := append([]Endpoint(nil), s.endpoints...)
+	s.mu.Unlock()
+	return s.health.rank(filterPenalized(endpoints))
+}
+
+// Generation 返回 sing-box 进程的代数。场景开始与结束时的代数不同，说明期间进程被重启，
+// 本地入站曾经中断，此时的失败与节点无关。
+func (s *Supervisor) Generation() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generation
+}
+
+func (s *Supervisor) nextGeneration() {
+	s.mu.Lock()
+	s.generation++
+	s.mu.Unlock()
+}
+
+// Health 返回节点的健康检查记录；尚未探测时 ok 为 false。
+func (s *Supervisor) Health(tag string) (Health, bool) {
+	return s.health.get(tag)
+}
+
+func (s *Supervisor) setEndpoints(endpoints []Endpoint) {
+	s.mu.Lock()
+	s.endpoints = endpoints
+	s.mu.Unlock()
+	s.readyOnce.Do(func() { close(s.ready) })
+	if len(endpoints) > 0 {
+		// 新的节点列表立即探测一轮
+		select {
+		case s.probeNow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// probeLoop 每隔 probeInterval（或节点列表变化时）探测全部节点，直到 ctx 结束。
+func (s *Supervisor) probeLoop(ctx context.Context) {
+	ticker := time.NewTicker(probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.probeNow:
+		}
+		s.mu.Lock()
+		endpoints := append([]Endpoint(nil), s.endpoints...)
+		s.mu.Unlock()
+		s.health.probeAll(ctx, endpoints)
+	}
+}
+
+func (s *Supervisor) loop(ctx context.Context) {
+	defer close(s.done)
+	backoff := supervisorMinBackoff
+	for {
+		cmd, endpoints, err := s.launch(ctx)
+		if err != nil {
+			fmt.Printf("⚠️ sing-box 启动失败，%s 后重试：%v\n", backoff, err)
+			s.setEndpoints(nil)
+			if !s.wait(ctx, backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+		s.setEndpoints(endpoints)
+		if cmd == nil {
+			// 未配置订阅：直连，等待订阅变更
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.reload:
+				continue
+			}
+		}
+
+		startedAt := time.Now()
+		exited := make(chan error, 1)
+		go func() { exited <- cmd.Wait() }()
+		select {
+		case <-ctx.Done():
+			_ = cmd.Process.Kill()
+			<-exited
+			s.setEndpoints(nil)
+			return
+		case <-s.reload:
+			fmt.Println("🔄 订阅已变更，重新加载 sing-box")
+			s.nextGeneration()
+			_ = cmd.Process.Kill()
+			<-exited
+			backoff = supervisorMinBackoff
+		case err := <-exited:
+			s.nextGeneration()
+			s.setEndpoints(nil)
+			if time.Since(startedAt) > supervisorMaxBackoff {
+				backoff = supervisorMinBackoff
+			}
+			fmt.Printf("⚠️ sing-box 进程退出(%v)，%s 后重启\n", err, backoff)
+			if !s.wait(ctx, backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff)
+		}
+	}
+}
+
+func (s *Supervisor) launch(ctx context.Context) (*exec.Cmd, []Endpoint, error) {
+	bin, endpoints, err := prepareSingBox(ctx)
+	if err != nil || bin == "" {
+		return nil, nil, err
+	}
+	cmd := exec.Command(bin, "run", "-c", singboxConfigFile, "--disable-color")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("start sing-box: %w", err)
+	}
+	firstPort := extractPort(endpoints[0].URL)
+	_ = waitPortReady(ctx, "127.0.0.1", firstPort, 10*time.Second)
+	fmt.Printf("🧭 sing-box 已启动，节点数：%d\n", len(endpoints))
+	return cmd, endpoints, nil
+}
+
+// wait 等待 d 或收到重新加载信号；ctx 结束时返回 false。
+func (s *Supervisor) wait(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-s.reload:
+		return true
+	case <-time.After(d):
+		return true
+	}
+}
+
+func nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > supervisorMaxBackoff {
+		d = supervisorMaxBackoff
+	}
+	return d
+}
@@ -2,6 +2,7 @@ package proxy
 
 // Endpoint is the resulting proxy URL for Playwright to consume.
 type Endpoint struct {
-	Tag string
-	URL string
+	Tag  string
+	URL  string
+	Type string // sing-box outbound 类型，例如 vmess、trojan
 }
@@ -0,0 +1,273 @@
+// Package selectors 从 YAML 加载 Vertex AI Studio 页面的定位器，支持覆盖文件与热重载。
+package selectors
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	playwright "github.com/playwright-community/playwright-go"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed defaults.yaml
+var defaultsYAML []byte
+
+// Strategy 是一种定位方式；css / role / text / label 只能设置一个。
+type Strategy struct {
+	CSS     string `yaml:"css,omitempty" json:"css,omitempty"`
+	Role    string `yaml:"role,omitempty" json:"role,omitempty"`
+	Name    string `yaml:"name,omitempty" json:"name,omitempty"`       // role 的可访问名称（正则）
+	Text    string `yaml:"text,omitempty" json:"text,omitempty"`       // 不区分大小写的文本子串
+	Label   string `yaml:"label,omitempty" json:"label,omitempty"`     // 关联 label 文本（正则）
+	HasText string `yaml:"hasText,omitempty" json:"hasText,omitempty"` // 只保留包含匹配文本的元素（正则）
+	Inner   string `yaml:"inner,omitempty" json:"inner,omitempty"`     // 在匹配元素内继续查找的 CSS
+
+	name, label, hasText *regexp.Regexp
+}
+
+// Set 是同一元素的一组候选策略，定位时按顺序用 Or 组合。
+type Set []Strategy
+
+// Sets 按名称索引定位器，例如 "prompt.input"。
+type Sets map[string]Set
+
+// Parse 解析并校验 YAML 定位器定义。
+func Parse(data []byte) (Sets, error) {
+	sets := Sets{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&sets); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := sets.compile(); err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+func (s Sets) compile() error {
+	var errs []error
+	for _, key := range s.Names() {
+		set := s[key]
+		if len(set) == 0 {
+			errs = append(errs, fmt.Errorf("  - %s: 至少需要一个策略", key))
+		}
+		for i := range set {
+			if err := set[i].compile(); err != nil {
+				errs = append(errs, fmt.Errorf("  - %s[%d]: %v", key, i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (st *Strategy) compile() error {
+	kinds := 0
+	for _, v := range []string{st.CSS, st.Role, st.Text, st.Label} {
+		if v != "" {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return errors.New("css / role / text / label 必须且只能设置一个")
+	}
+	if st.Name != "" && st.Role == "" {
+		return errors.New("name 只能与 role 一起使用")
+	}
+	var err error
+	for _, re := range []struct {
+		src string
+		dst **regexp.Regexp
+	}{{st.Name, &st.name}, {st.Label, &st.label}, {st.HasText, &st.hasText}} {
+		if re.src == "" {
+			continue
+		}
+		if *re.dst, err = regexp.Compile(re.src); err != nil {
+			return fmt.Errorf("正则 %q 无效: %v", re.src, err)
+		}
+	}
+	return nil
+}
+
+// Names 返回排序后的定位器名称。
+func (s Sets) Names() []string {
+	names := make([]string, 0, len(s))
+	for k := range s {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Locate 在 root 内按策略顺序组合出定位器。
+func (s Set) Locate(root playwright.Locator) playwright.Locator {
+	var loc playwright.Locator
+	for _, st := range s {
+		l := st.Locate(root)
+		if loc == nil {
+			loc = l
+		} else {
+			loc = loc.Or(l)
+		}
+	}
+	if loc == nil {
+		// 空集合（未知名称）：返回一个不会匹配任何元素的定位器
+		return root.Locator("[data-selector-registry-missing]")
+	}
+	return loc
+}
+
+// Locate 在 root 内按单个策略定位。
+func (st Strategy) Locate(root playwright.Locator) playwright.Locator {
+	var loc playwright.Locator
+	switch {
+	case st.CSS != "":
+		loc = root.Locator(st.CSS)
+	case st.Role != "":
+		opts := playwright.LocatorGetByRoleOptions{}
+		if st.name != nil {
+			opts.Name = st.name
+		}
+		loc = root.GetByRole(playwright.AriaRole(st.Role), opts)
+	case st.Text != "":
+		loc = root.GetByText(st.Text, playwright.LocatorGetByTextOptions{Exact: playwright.Bool(false)})
+	default:
+		loc = root.GetByLabel(st.label)
+	}
+	if st.hasText != nil {
+		loc = loc.Filter(playwright.LocatorFilterOptions{HasText: st.hasText})
+	}
+	if st.Inner != "" {
+		loc = loc.Locator(st.Inner)
+	}
+	return loc
+}
+
+// Defaults 返回内置的定位器定义。
+func Defaults() Sets {
+	sets, err := Parse(defaultsYAML)
+	if err != nil {
+		panic(fmt.Sprintf("selectors: 内置 defaults.yaml 无效:\n%v", err))
+	}
+	return sets
+}
+
+// Registry 保存当前生效的定位器：内置默认值叠加可选的覆盖文件。
+type Registry struct {
+	path string
+
+	mu       sync.RWMutex
+	sets     Sets
+	modTime  time.Time
+	loadedAt time.Time
+}
+
+// New 加载内置定义与覆盖文件 path；path 为空或文件不存在时只使用内置定义。
+func New(path string) (*Registry, error) {
+	r := &Registry{path: path, sets: Defaults(), loadedAt: time.Now()}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Path 返回覆盖文件路径。
+func (r *Registry) Path() string { return r.path }
+
+// Reload 重新读取覆盖文件；解析失败时保留当前定义并返回错误。
+func (r *Registry) Reload() error {
+	sets := Defaults()
+	var modTime time.Time
+	if r.path != "" {
+		info, err := os.Stat(r.path)
+		switch {
+		case err == nil:
+			data, err := os.ReadFile(r.path)
+			if err != nil {
+				return fmt.Errorf("读取定位器文件 %s 失败: %w", r.path, err)
+			}
+			override, err := Parse(data)
+			if err != nil {
+				return fmt.Errorf("解析定位器文件 %s 失败:\n%w", r.path, err)
+			}
+			for key, set := range override {
+				if _, ok := sets[key]; !ok {
+					return fmt.Errorf("定位器文件 %s: 未知条目 %q", r.path, key)
+				}
+				sets[key] = set
+			}
+			modTime = info.ModTime()
+		case os.IsNotExist(err):
+		default:
+			return fmt.Errorf("读取定位器文件 %s 失败: %w", r.path, err)
+		}
+	}
+	r.mu.Lock()
+	r.sets = sets
+	r.modTime = modTime
+	r.loadedAt = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// Get 返回名称对应的策略集合，不存在时为空集合。
+func (r *Registry) Get(name string) Set {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.sets[name]
+}
+
+// Snapshot 返回当前全部定义的副本及加载时间。
+func (r *Registry) Snapshot() (Sets, time.Time) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(Sets, len(r.sets))
+	for k, v := range r.sets {
+		out[k] = append(Set(nil), v...)
+	}
+	return out, r.loadedAt
+}
+
+// Watch 每隔 interval 检查覆盖文件的修改时间，变化（含创建、删除）时重新加载，直到 ctx 结束。
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	if r.path == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var modTime time.Time
+		if info, err := os.Stat(r.path); err == nil {
+			modTime = info.ModTime()
+		}
+		r.mu.RLock()
+		changed := !modTime.Equal(r.modTime)
+		r.mu.RUnlock()
+		if !changed {
+			continue
+		}
+		if err := r.Reload(); err != nil {
+			fmt.Printf("⚠️ 定位器热重载失败，继续使用旧定义: %v\n", err)
+			// 记录修改时间，避免对同一个错误版本反复报错
+			r.mu.Lock()
+			r.modTime = modTime
+			r.mu.Unlock()
+			continue
+		}
+		fmt.Printf("🔄 定位器已重新加载: %s\n", r.path)
+	}
+}
@@ -0,0 +1,259 @@
+package steps
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	playwright "github.com/playwright-community/playwright-go"
+)
+
+// generationURL 匹配 Studio 调用的生成接口（含流式版本）。
+var generationURL = regexp.MustCompile(`(?i)(stream)?generatecontent|:predict|generateimages`)
+
+// CapturedImage 是从生成接口响应中解码出的一张图片。
+type CapturedImage struct {
+	MimeType string
+	Data     []byte
+}
+
+// ImageCapture 监听页面的生成接口响应，解码其中 inlineData 图片部分、文本回复与结束原因。
+// Studio 还会调用同一接口生成标题、建议等，因此只处理 Arm 之后的第一个生成请求。
+type ImageCapture struct {
+	mu          sync.Mutex
+	armed       bool
+	primary     playwright.Request // 提交提示词后发出的第一个生成请求
+	images      []CapturedImage
+	seen        map[[sha256.Size]byte]bool
+	last        time.Time
+	exhausted   bool
+	text        []string
+	blockReason string
+	finishedAt  time.Time
+}
+
+// generationReply 是从一个响应体中解析出的内容。
+type generationReply struct {
+	images      []CapturedImage
+	text        []string
+	finished    bool   // 出现了 finishReason 或 promptFeedback.blockReason
+	blockReason string // 安全类的 finishReason 或 blockReason
+}
+
+// StartImageCapture 在 page 上注册请求与响应监听，需在提交提示词之前调用。
+func StartImageCapture(page playwright.Page) *ImageCapture {
+	c := &ImageCapture{seen: map[[sha256.Size]byte]bool{}}
+	page.OnRequest(func(req playwright.Request) {
+		if !generationURL.MatchString(req.URL()) {
+			return
+		}
+		c.mu.Lock()
+		if c.armed && c.primary == nil {
+			c.primary = req
+		}
+		c.mu.Unlock()
+	})
+	page.OnResponse(func(resp playwright.Response) {
+		if !generationURL.MatchString(resp.URL()) {
+			return
+		}
+		// 事件回调中同步读取 body 会阻塞 Playwright 的事件分发
+		go c.handle(resp)
+	})
+	return c
+}
+
+// Arm 在点击提交之前调用，之后发出的第一个生成请求被视为本次生成。
+func (c *ImageCapture) Arm() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.armed = true
+}
+
+func (c *ImageCapture) handle(resp playwright.Response) {
+	// 标题、建议等辅助调用的 429、图片、文本与结束原因都不代表本次生成的结果
+	if !c.isPrimary(resp) {
+		return
+	}
+	if resp.Status() == http.StatusTooManyRequests {
+		fmt.Printf("⚠️ Generation API returned 429: %s\n", resp.URL())
+		c.mu.Lock()
+		c.exhausted = true
+		c.mu.Unlock()
+		return
+	}
+	body, err := resp.Body()
+	if err != nil || len(body) == 0 {
+		return
+	}
+	reply := decodeReply(body)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.text = append(c.text, reply.text...)
+	if reply.blockReason != "" && c.blockReason == "" {
+		c.blockReason = reply.blockReason
+		fmt.Printf("⛔ Generation blocked: %s\n", reply.blockReason)
+	}
+	if reply.finished {
+		c.finishedAt = time.Now()
+	}
+	if len(reply.images) == 0 {
+		return
+	}
+	for _, img := range reply.images {
+		sum := sha256.Sum256(img.Data)
+		if c.seen[sum] {
+			continue
+		}
+		c.seen[sum] = true
+		c.images = append(c.images, img)
+		c.last = time.Now()
+	}
+	fmt.Printf("🟦 Captured %d image(s) from %s\n", len(c.images), resp.URL())
+}
+
+// isPrimary 报告 resp 是否属于 Arm 之后的第一个生成请求。
+func (c *ImageCapture) isPrimary(resp playwright.Response) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.primary != nil && resp.Request() == c.primary
+}
+
+// Images 返回已捕获的图片以及最后一张图片到达的时间。
+func (c *ImageCapture) Images() ([]CapturedImage, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CapturedImage(nil), c.images...), c.last
+}
+
+// Text 返回模型的文本回复（不含思考过程），没有时为空。
+func (c *ImageCapture) Text() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return strings.TrimSpace(strings.Join(c.text, ""))
+}
+
+// BlockReason 返回安全拦截原因（如 SAFETY、PROHIBITED_CONTENT），未被拦截时为空。
+func (c *ImageCapture) BlockReason() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blockReason
+}
+
+// Finished 返回本次生成的响应带着结束原因到达的时间，尚未结束时为零值。
+func (c *ImageCapture) Finished() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.finishedAt
+}
+
+// Exhausted 报告生成接口是否返回过 429。
+func (c *ImageCapture) Exhausted() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.exhausted
+}
+
+// decodeReply 解析 JSON、JSON 数组流或 SSE（data: 行）格式的响应体，
+// 收集所有 inlineData / inline_data 中的图片、文本部分与结束原因。
+func decodeReply(body []byte) generationReply {
+	body = bytes.TrimPrefix(bytes.TrimSpace(body), []byte(")]}'"))
+	var reply generationReply
+	var doc any
+	if err := json.Unmarshal(body, &doc); err == nil {
+		collectReply(doc, &reply)
+		return reply
+	}
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(line), []byte("data:")))
+		line = bytes.Trim(line, ",[]")
+		if len(line) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(line, &doc); err == nil {
+			collectReply(doc, &reply)
+		}
+	}
+	return reply
+}
+
+// safetyFinish 匹配表示内容被拦截的 finishReason。
+var safetyFinish = regexp.MustCompile(`SAFETY|PROHIBITED|BLOCKLIST|SPII|RECITATION`)
+
+func collectReply(v any, out *generationReply) {
+	switch node := v.(type) {
+	case []any:
+		for _, item := range node {
+			collectReply(item, out)
+		}
+	case map[string]any:
+		for _, key := range []string{"finishReason", "finish_reason"} {
+			reason, _ := node[key].(string)
+			if reason == "" {
+				continue
+			}
+			out.finished = true
+			if safetyFinish.MatchString(reason) && out.blockReason == "" {
+				out.blockReason = reason
+			}
+		}
+		for _, key := range []string{"blockReason", "block_reason"} {
+			if reason, _ := node[key].(string); reason != "" && reason != "BLOCKED_REASON_UNSPECIFIED" {
+				out.finished = true
+				out.blockReason = reason
+				if msg, _ := node["blockReasonMessage"].(string); msg != "" {
+					out.blockReason += ": " + msg
+				}
+			}
+		}
+		// parts 中的文本；thought 为 true 的是思考过程，不记录
+		if text, ok := node["text"].(string); ok && text != "" {
+			if thought, _ := node["thought"].(bool); !thought {
+				out.text = append(out.text, text)
+			}
+		}
+		for _, key := range []string{"inlineData", "inline_data"} {
+			part, ok := node[key].(map[string]any)
+			if !ok {
+				continue
+			}
+			mimeType, _ := part["mimeType"].(string)
+			if mimeType == "" {
+				mimeType, _ = part["mime_type"].(string)
+			}
+			data, _ := part["data"].(string)
+			if !strings.HasPrefix(mimeType, "image/") || data == "" {
+				continue
+			}
+			raw, err := base64.StdEncoding.DecodeString(data)
+			if err != nil {
+				if raw, err = base64.URLEncoding.DecodeString(data); err != nil {
+					continue
+				}
+			}
+			out.images = append(out.images, CapturedImage{MimeType: mimeType, Data: raw})
+		}
+		for _, child := range node {
+			collectReply(child, out)
+		}
+	}
+}
+
+// imageExt 根据 MIME 类型选择扩展名，未知类型按 png 处理。
+func imageExt(mimeType string) string {
+	switch strings.ToLower(mimeType) {
+	case "image/jpeg", "image/jpg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	default:
+		return ".png"
+	}
+}
@@ -1,24 +1,28 @@
 package steps
 
 import (
-	"regexp"
-
 	playwright "github.com/playwright-community/playwright-go"
 )
 
+// CookieBarVisible reports whether the cookie notification bar is shown.
+func CookieBarVisible(page playwright.Page) bool {
+	visible, _ := cookieBar(page).First().IsVisible()
+	return visible
+}
+
+func cookieBar(page playwright.Page) playwright.Locator {
+	return locate(page, "cookies.bar")
+}
+
 // AcceptCookieBar clicks the cookie accept button if visible.
 func AcceptCookieBar(page playwright.Page) (bool, error) {
-	bar := page.Locator("#glue-cookie-notification-bar-1").Or(page.Locator(".glue-cookie-notification-bar"))
+	bar := cookieBar(page)
 	visible, _ := bar.First().IsVisible()
 	if !visible {
 		return false, nil
 	}
 
-	button := bar.Locator("button.glue-cookie-notification-bar__accept").Or(
-		bar.GetByRole("button", playwright.LocatorGetByRoleOptions{
-			Name: regexp.MustCompile("(?i)ok,?\\s*got it"),
-		}),
-	)
+	button := locateIn(bar, "cookies.accept")
 	btnVisible, _ := button.First().IsVisible()
 	if !btnVisible {
 		return false, nil
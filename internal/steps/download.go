@@ -2,6 +2,7 @@ package steps
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,68 +10,147 @@ import (
 	"time"
 
 	playwright "github.com/playwright-community/playwright-go"
+
+	"vertex-nano-banana-unlimited/internal/outcome"
 )
 
-type DownloadOutcome string
+// DownloadOutcome 是场景结果代码，定义在 outcome 包中，供 config 与 proxy 使用而无需依赖本包。
+type DownloadOutcome = outcome.Outcome
 
 const (
-	DownloadOutcomeDownloaded DownloadOutcome = "downloaded"
-	DownloadOutcomeExhausted  DownloadOutcome = "exhausted"
-	DownloadOutcomeNone       DownloadOutcome = "none"
+	DownloadOutcomeDownloaded   = outcome.Downloaded
+	DownloadOutcomeExhausted    = outcome.Exhausted
+	DownloadOutcomeDeadline     = outcome.Deadline
+	DownloadOutcomeTimeout      = outcome.Timeout
+	DownloadOutcomeCancelled    = outcome.Cancelled
+	DownloadOutcomeSubmitFailed = outcome.SubmitFailed
+	DownloadOutcomeTokenInvalid = outcome.TokenInvalid
+	DownloadOutcomeBlocked      = outcome.Blocked
+	DownloadOutcomeText         = outcome.Text
+	DownloadOutcomeNone         = outcome.None
 )
 
-// DownloadImage waits for the download button or a 429 notice, then saves with a timestamped name.
-// Returns outcome and saved path (empty if not downloaded).
-func DownloadImage(ctx context.Context, page playwright.Page, dir string, maxWait time.Duration) (DownloadOutcome, string, error) {
-	button := page.Locator("button[cfctooltip=\"Download image\"]").Or(
-		page.Locator("button[cfctooltip=\"下载图片\"]"),
-	).First()
-	exhaust := page.Locator("a[href*=\"vertex-ai/generative-ai/docs/error-code-429\"]").
-		Or(page.GetByText("Resource exhausted", playwright.PageGetByTextOptions{Exact: playwright.Bool(false)})).
-		Or(page.GetByText("resource exhausted", playwright.PageGetByTextOptions{Exact: playwright.Bool(false)})).
-		Or(page.GetByText("check quota", playwright.PageGetByTextOptions{Exact: playwright.Bool(false)})).
-		Or(page.GetByText("Deadline expired before operation could complete.", playwright.PageGetByTextOptions{Exact: playwright.Bool(false)})).
-		Or(page.GetByText("未能提交提示", playwright.PageGetByTextOptions{Exact: playwright.Bool(false)})).
-		Or(page.GetByText("The operation was cancelled", playwright.PageGetByTextOptions{Exact: playwright.Bool(false)})).
-		Or(page.GetByText("Recaptcha token is invalid, please refresh the page or log in, and try again.", playwright.PageGetByTextOptions{Exact: playwright.Bool(false)}))
+// notices 按优先级列出页面提示对应的结果；"未能提交提示" 常与更具体的原因同时出现，放在最后。
+var notices = []struct {
+	selector string
+	outcome  DownloadOutcome
+}{
+	{"download.tokenInvalid", DownloadOutcomeTokenInvalid},
+	{"download.blocked", DownloadOutcomeBlocked},
+	{"download.exhausted", DownloadOutcomeExhausted},
+	{"download.deadline", DownloadOutcomeDeadline},
+	{"download.cancelled", DownloadOutcomeCancelled},
+	{"download.submitFailed", DownloadOutcomeSubmitFailed},
+}
+
+// captureSettle 是最后一张捕获图片之后等待更多候选图片的时间。
+const captureSettle = 3 * time.Second
+
+// DownloadImages waits for generated images or a failure notice. Images captured from the
+// generation API response (capture may be nil) are preferred; the download button is the fallback.
+// Returns outcome and saved paths (empty if not downloaded); DownloadOutcomeTimeout if maxWait elapses.
+func DownloadImages(ctx context.Context, page playwright.Page, capture *ImageCapture, dir string, maxWait time.Duration) (DownloadOutcome, []string, error) {
+	button := locate(page, "download.button").First()
+	var anyNotice playwright.Locator
+	for _, n := range notices {
+		if l := locate(page, n.selector); anyNotice == nil {
+			anyNotice = l
+		} else {
+			anyNotice = anyNotice.Or(l)
+		}
+	}
 
 	deadline := time.Now().Add(maxWait)
+	buttonSeen := false
 	for time.Now().Before(deadline) {
 		select {
 		case <-ctx.Done():
-			return DownloadOutcomeNone, "", ctx.Err()
+			return DownloadOutcomeNone, nil, ctx.Err()
 		default:
 		}
-		if vis, _ := exhaust.First().IsVisible(); vis {
-			fmt.Println("⚠️ 429/quota notice detected")
-			return DownloadOutcomeExhausted, "", nil
+		if capture != nil {
+			images, last := capture.Images()
+			if len(images) > 0 && (buttonSeen || time.Since(last) >= captureSettle) {
+				paths, err := saveCaptured(images, dir)
+				if err != nil {
+					return DownloadOutcomeNone, nil, err
+				}
+				return DownloadOutcomeDownloaded, paths, nil
+			}
+			if capture.Exhausted() {
+				return DownloadOutcomeExhausted, nil, nil
+			}
+			// 响应已结束但没有图片：被拦截或只回复了文本，无需等满 maxWait
+			if done := capture.Finished(); len(images) == 0 && !done.IsZero() && time.Since(done) >= captureSettle {
+				if capture.BlockReason() != "" {
+					return DownloadOutcomeBlocked, nil, nil
+				}
+				fmt.Println("ℹ️ Model replied without an image")
+				return DownloadOutcomeText, nil, nil
+			}
+		}
+		if vis, _ := anyNotice.First().IsVisible(); vis {
+			outcome := classifyNotice(page)
+			fmt.Printf("⚠️ Failure notice detected: %s\n", outcome)
+			return outcome, nil, nil
 		}
 		if vis, _ := button.IsVisible(); vis {
-			fmt.Println("🟦 Download button visible")
-			goto click
+			if capture == nil || buttonSeen {
+				fmt.Println("🟦 Download button visible")
+				return clickDownload(ctx, page, button, dir)
+			}
+			// 按钮出现时响应通常已到达，再给捕获一轮机会
+			buttonSeen = true
 		}
 		time.Sleep(1 * time.Second)
 	}
-	return DownloadOutcomeNone, "", nil
+	return DownloadOutcomeTimeout, nil, nil
+}
 
-click:
-	select {
-	case <-ctx.Done():
-		return DownloadOutcomeNone, "", ctx.Err()
-	default:
+// classifyNotice 返回第一个可见提示对应的结果。
+func classifyNotice(page playwright.Page) DownloadOutcome {
+	for _, n := range notices {
+		if vis, _ := locate(page, n.selector).First().IsVisible(); vis {
+			return n.outcome
+		}
+	}
+	// 提示在两次检查之间消失，按最常见的配额问题处理
+	return DownloadOutcomeExhausted
+}
+
+func saveCaptured(images []CapturedImage, dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
 	}
+	now := time.Now()
+	var paths []string
+	for i, img := range images {
+		// 并发场景共用同一目录，加上内容哈希前缀避免同一毫秒内的文件互相覆盖
+		sum := sha256.Sum256(img.Data)
+		filename := fmt.Sprintf("generated-%d_%s_%s_%x%s", i+1, now.Format("20060102"), now.Format("150405.000"), sum[:4], imageExt(img.MimeType))
+		target := filepath.Join(dir, filename)
+		if err := os.WriteFile(target, img.Data, 0o644); err != nil {
+			return paths, err
+		}
+		fmt.Printf("🟦 Captured image saved to: %s\n", target)
+		paths = append(paths, target)
+	}
+	return paths, nil
+}
+
+func clickDownload(ctx context.Context, page playwright.Page, button playwright.Locator, dir string) (DownloadOutcome, []string, error) {
 	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return DownloadOutcomeNone, "", err
+		return DownloadOutcomeNone, nil, err
 	}
 	download, err := page.ExpectDownload(func() error {
 		return button.Click(playwright.LocatorClickOptions{Force: playwright.Bool(true)})
 	})
 	if err != nil {
-		return DownloadOutcomeNone, "", err
+		return DownloadOutcomeNone, nil, err
 	}
 	select {
 	case <-ctx.Done():
-		return DownloadOutcomeNone, "", ctx.Err()
+		return DownloadOutcomeNone, nil, ctx.Err()
 	default:
 	}
 	suggested := download.SuggestedFilename()
@@ -80,8 +160,8 @@ click:
 	filename := fmt.Sprintf("%s_%s_%s%s", base, now.Format("20060102"), now.Format("150405.000"), ext)
 	target := filepath.Join(dir, filename)
 	if err := download.SaveAs(target); err != nil {
-		return DownloadOutcomeNone, "", err
+		return DownloadOutcomeNone, nil, err
 	}
 	fmt.Printf("🟦 Image downloaded to: %s\n", target)
-	return DownloadOutcomeDownloaded, target, nil
+	return DownloadOutcomeDownloaded, []string{target}, nil
 }
@@ -1,6 +1,7 @@
 package steps
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -11,11 +12,7 @@ import (
 
 // OpenModelSettings opens the model settings panel by clicking the header.
 func OpenModelSettings(page playwright.Page) (bool, error) {
-	header := page.GetByRole("button", playwright.PageGetByRoleOptions{
-		Name: regexp.MustCompile("(?i)model settings|模型设置"),
-	}).Or(page.Locator("ai-llm-collapsible-model-settings .collapsible-panel__title", playwright.PageLocatorOptions{
-		HasText: "模型设置",
-	})).First()
+	header := locate(page, "model.settingsHeader").First()
 
 	vis, _ := header.IsVisible()
 	if !vis {
@@ -25,11 +22,24 @@ func OpenModelSettings(page playwright.Page) (bool, error) {
 	return true, header.Click(playwright.LocatorClickOptions{Force: playwright.Bool(true)})
 }
 
+// ErrOptionUnavailable means the model settings panel does not offer the requested value.
+// The pipeline does not retry steps failing with it.
+var ErrOptionUnavailable = errors.New("option not available")
+
+// ModelSettingsOpen reports whether the model settings panel is expanded,
+// judged by the output resolution combobox being visible.
+func ModelSettingsOpen(page playwright.Page) bool {
+	vis, _ := resolutionCombo(page).IsVisible()
+	return vis
+}
+
+func resolutionCombo(page playwright.Page) playwright.Locator {
+	return locate(page, "model.resolution")
+}
+
 // SetOutputResolution chooses a resolution option in the combobox.
 func SetOutputResolution(page playwright.Page, target string) (bool, error) {
-	combo := page.GetByRole("combobox", playwright.PageGetByRoleOptions{
-		Name: regexp.MustCompile("(?i)output resolution|输出分辨率"),
-	})
+	combo := resolutionCombo(page)
 
 	vis, _ := combo.IsVisible()
 	if !vis {
@@ -68,16 +78,14 @@ func SetTemperature(page playwright.Page, temperature float64) (bool, error) {
 	}
 
 	// Prefer label text match (Chinese/English) to avoid brittle IDs
-	tempContainer := page.Locator("div", playwright.PageLocatorOptions{
-		HasText: regexp.MustCompile("(?i)温度|temperature"),
-	}).First()
+	tempContainer := locate(page, "model.temperature").First()
 	vis, _ := tempContainer.IsVisible()
 	if !vis {
 		return false, nil
 	}
 
 	// Find the slider input element
-	sliderInput := tempContainer.Locator("input[type=\"range\"][min=\"0\"][max=\"2\"]").First()
+	sliderInput := locateIn(tempContainer, "model.temperatureInput").First()
 	vis, _ = sliderInput.IsVisible()
 	if !vis {
 		return false, nil
@@ -94,11 +102,7 @@ func SetTemperature(page playwright.Page, temperature float64) (bool, error) {
 	}
 
 	// Click on the slider at the calculated position
-	slider := tempContainer.Locator("mat-slider").First()
-	if vis, _ := slider.IsVisible(); !vis {
-		// fallback: role slider in the same container
-		slider = tempContainer.GetByRole("slider").First()
-	}
+	slider := locateIn(tempContainer, "model.temperatureSlider").First()
 	_ = slider.ScrollIntoViewIfNeeded()
 
 	// Get slider dimensions to calculate click position
@@ -134,3 +138,80 @@ func SetTemperature(page playwright.Page, temperature float64) (bool, error) {
 	// The aria-valuetext should contain our target value
 	return currentValue != "", nil
 }
+
+// SetAspectRatio chooses the output aspect ratio (e.g. "16:9") in the model settings panel.
+func SetAspectRatio(page playwright.Page, ratio string) (bool, error) {
+	combo := locate(page, "model.aspectRatio")
+	if vis, _ := combo.IsVisible(); !vis {
+		return false, fmt.Errorf("%w: aspect ratio setting is not offered in model settings", ErrOptionUnavailable)
+	}
+	pattern := regexp.MustCompile(fmt.Sprintf(`^\s*%s\b`, regexp.QuoteMeta(ratio)))
+	return chooseOption(page, combo, pattern, "aspect ratio", ratio)
+}
+
+// SetCandidateCount sets the number of candidates (responses) generated per prompt.
+// The panel shows it either as a combobox or as a number input depending on the model.
+func SetCandidateCount(page playwright.Page, count int) (bool, error) {
+	target := fmt.Sprint(count)
+
+	combo := locate(page, "model.candidateCount")
+	if vis, _ := combo.IsVisible(); vis {
+		pattern := regexp.MustCompile(fmt.Sprintf(`^\s*%d\s*$`, count))
+		return chooseOption(page, combo, pattern, "candidate count", target)
+	}
+
+	input := locate(page, "model.candidateCountInput").First()
+	if vis, _ := input.IsVisible(); !vis {
+		return false, fmt.Errorf("%w: candidate count setting is not offered in model settings", ErrOptionUnavailable)
+	}
+	if maxAttr, _ := input.GetAttribute("max"); maxAttr != "" {
+		var limit int
+		if _, err := fmt.Sscan(maxAttr, &limit); err == nil && count > limit {
+			return false, fmt.Errorf("%w: candidate count %d exceeds maximum %d", ErrOptionUnavailable, count, limit)
+		}
+	}
+	_ = input.ScrollIntoViewIfNeeded()
+	if err := input.Fill(target); err != nil {
+		return false, err
+	}
+	_ = input.Press("Tab")
+	page.WaitForTimeout(300)
+	val, _ := input.InputValue()
+	return strings.TrimSpace(val) == target, nil
+}
+
+// chooseOption opens combo and clicks the option matching pattern. When no option
+// matches, the listbox is closed and an ErrOptionUnavailable listing the offered values is returned.
+func chooseOption(page playwright.Page, combo playwright.Locator, pattern *regexp.Regexp, what, target string) (bool, error) {
+	_ = combo.ScrollIntoViewIfNeeded()
+	if err := combo.Click(playwright.LocatorClickOptions{Force: playwright.Bool(true)}); err != nil {
+		return false, err
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	options := locate(page, "overlay.options")
+	if err := options.First().WaitFor(playwright.LocatorWaitForOptions{
+		State:   playwright.WaitForSelectorStateVisible,
+		Timeout: playwright.Float(3000),
+	}); err != nil {
+		// 列表没有展开，交给重试
+		return false, nil
+	}
+	texts, _ := options.AllInnerTexts()
+	for i, text := range texts {
+		if !pattern.MatchString(strings.TrimSpace(text)) {
+			continue
+		}
+		if err := options.Nth(i).Click(playwright.LocatorClickOptions{Force: playwright.Bool(true)}); err != nil {
+			return false, err
+		}
+		page.WaitForTimeout(300)
+		val, _ := combo.InnerText()
+		return strings.Contains(val, target), nil
+	}
+	_ = page.Keyboard().Press("Escape")
+	for i := range texts {
+		texts[i] = strings.TrimSpace(texts[i])
+	}
+	return false, fmt.Errorf("%w: %s %q not in [%s]", ErrOptionUnavailable, what, target, strings.Join(texts, ", "))
+}
@@ -0,0 +1,222 @@
+package steps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	playwright "github.com/playwright-community/playwright-go"
+)
+
+var (
+	// ErrNotCompleted 表示步骤函数返回 false（元素不可见、值未生效等）。
+	ErrNotCompleted = errors.New("not completed")
+	// ErrPostcondition 表示步骤执行后校验未通过。
+	ErrPostcondition = errors.New("postcondition not met")
+	// ErrStepTimeout 表示单次尝试超过步骤超时。
+	ErrStepTimeout = errors.New("step timed out")
+)
+
+// RetryPolicy 描述步骤失败后的重试方式。
+type RetryPolicy struct {
+	Attempts int           // 总尝试次数，<1 视为 1
+	Backoff  time.Duration // 两次尝试之间的等待
+}
+
+// Step 是流水线中的一个页面操作。
+type Step interface {
+	Name() string
+	// Timeout 是单次尝试的上限，0 表示不限制。
+	Timeout() time.Duration
+	Retry() RetryPolicy
+	// Precondition 返回 false 时跳过该步骤（例如 cookie 栏不存在）。
+	Precondition(page playwright.Page) (bool, error)
+	Run(page playwright.Page) (bool, error)
+	// Postcondition 校验步骤效果，返回 false 视为本次尝试失败。
+	Postcondition(page playwright.Page) (bool, error)
+}
+
+// Func 用函数字段实现 Step；Pre/Post 为空表示不检查。
+type Func struct {
+	StepName    string
+	StepTimeout time.Duration
+	Policy      RetryPolicy
+	Pre         func(page playwright.Page) (bool, error)
+	Do          func(page playwright.Page) (bool, error)
+	Post        func(page playwright.Page) (bool, error)
+}
+
+func (f Func) Name() string           { return f.StepName }
+func (f Func) Timeout() time.Duration { return f.StepTimeout }
+func (f Func) Retry() RetryPolicy     { return f.Policy }
+
+func (f Func) Precondition(page playwright.Page) (bool, error) {
+	if f.Pre == nil {
+		return true, nil
+	}
+	return f.Pre(page)
+}
+
+func (f Func) Run(page playwright.Page) (bool, error) {
+	return f.Do(page)
+}
+
+func (f Func) Postcondition(page playwright.Page) (bool, error) {
+	if f.Post == nil {
+		return true, nil
+	}
+	return f.Post(page)
+}
+
+// StepError 记录失败的步骤以及已经尝试的次数。
+type StepError struct {
+	Step     string
+	Attempts int
+	Err      error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("%s failed after %d attempt(s): %v", e.Step, e.Attempts, e.Err)
+}
+
+func (e *StepError) Unwrap() error { return e.Err }
+
+// Hooks 让调用方观察流水线进度，所有字段都可为空。
+type Hooks struct {
+	OnStart   func(name string)
+	OnSkip    func(name string)
+	OnRetry   func(name string, attempt int, err error)
+	OnSuccess func(name string, attempts int, elapsed time.Duration)
+	OnFailure func(name string, attempts int, elapsed time.Duration, err error)
+}
+
+// Pipeline 按顺序执行步骤，每个成功的步骤之后停顿 Pause。
+type Pipeline struct {
+	Steps []Step
+	Pause time.Duration
+	Hooks Hooks
+}
+
+// Run 执行所有步骤，遇到第一个最终失败的步骤时返回 *StepError。
+// 取消、超出截止时间、ErrOptionUnavailable 以及超时后迟迟不结束的尝试不会重试。
+func (p Pipeline) Run(ctx context.Context, page playwright.Page) error {
+	for _, s := range p.Steps {
+		if err := ctx.Err(); err != nil {
+			return &StepError{Step: s.Name(), Err: err}
+		}
+		ok, err := s.Precondition(page)
+		if err != nil {
+			return &StepError{Step: s.Name(), Err: fmt.Errorf("precondition: %w", err)}
+		}
+		if !ok {
+			if p.Hooks.OnSkip != nil {
+				p.Hooks.OnSkip(s.Name())
+			}
+			continue
+		}
+		if err := p.runStep(ctx, page, s); err != nil {
+			return err
+		}
+		time.Sleep(p.Pause)
+	}
+	return nil
+}
+
+func (p Pipeline) runStep(ctx context.Context, page playwright.Page, s Step) error {
+	name := s.Name()
+	policy := s.Retry()
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+	if p.Hooks.OnStart != nil {
+		p.Hooks.OnStart(name)
+	}
+	started := time.Now()
+	var err error
+	for attempt := 1; attempt <= policy.Attempts; attempt++ {
+		if attempt > 1 {
+			if p.Hooks.OnRetry != nil {
+				p.Hooks.OnRetry(name, attempt, err)
+			}
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				attempt = policy.Attempts
+				continue
+			case <-time.After(policy.Backoff):
+			}
+		}
+		var pending <-chan error
+		pending, err = attemptStep(ctx, page, s)
+		stale := false // 超时的尝试在宽限期内仍未结束
+		if pending != nil && attempt < policy.Attempts {
+			// 超时的尝试仍在操作页面：最多再等一个步骤超时让它结束，避免重复点击提交、重复输入或上传。
+			// 它最终成功则视为本步骤成功；仍未结束则放弃重试，直接以超时失败。
+			grace := time.NewTimer(s.Timeout())
+			select {
+			case late := <-pending:
+				if late == nil {
+					err = nil
+				}
+			case <-grace.C:
+				stale = true
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			grace.Stop()
+		}
+		if err == nil {
+			if p.Hooks.OnSuccess != nil {
+				p.Hooks.OnSuccess(name, attempt, time.Since(started))
+			}
+			return nil
+		}
+		if stale || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrOptionUnavailable) {
+			stepErr := &StepError{Step: name, Attempts: attempt, Err: err}
+			if p.Hooks.OnFailure != nil {
+				p.Hooks.OnFailure(name, attempt, time.Since(started), stepErr)
+			}
+			return stepErr
+		}
+	}
+	stepErr := &StepError{Step: name, Attempts: policy.Attempts, Err: err}
+	if p.Hooks.OnFailure != nil {
+		p.Hooks.OnFailure(name, policy.Attempts, time.Since(started), stepErr)
+	}
+	return stepErr
+}
+
+// attemptStep 执行一次 Run + Postcondition。超时后返回 ErrStepTimeout 以及仍在后台运行的
+// 这次尝试的结果通道（其余情况为 nil），调用方重试前需要等它结束。
+func attemptStep(ctx context.Context, page playwright.Page, s Step) (<-chan error, error) {
+	done := make(chan error, 1)
+	go func() {
+		ok, err := s.Run(page)
+		switch {
+		case err != nil:
+		case !ok:
+			err = ErrNotCompleted
+		default:
+			if ok, err = s.Postcondition(page); err == nil && !ok {
+				err = ErrPostcondition
+			}
+		}
+		done <- err
+	}()
+
+	var timeout <-chan time.Time
+	if d := s.Timeout(); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case err := <-done:
+		return nil, err
+	case <-timeout:
+		return done, fmt.Errorf("%w after %s", ErrStepTimeout, s.Timeout())
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -1,15 +1,19 @@
 package steps
 
 import (
-	"regexp"
 	"strings"
 
 	playwright "github.com/playwright-community/playwright-go"
 )
 
+// PromptInput returns the prompt text box.
+func PromptInput(page playwright.Page) playwright.Locator {
+	return locate(page, "prompt.input").First()
+}
+
 // EnterPrompt types text into the prompt box.
 func EnterPrompt(page playwright.Page, text string) (bool, error) {
-	box := page.Locator("ai-llm-prompt-input-box textarea, ai-llm-prompt-input-box [role=\"textbox\"], ai-llm-prompt-input-box [contenteditable=\"true\"]").First()
+	box := PromptInput(page)
 	visible, _ := box.IsVisible()
 	if !visible {
 		return false, nil
@@ -52,11 +56,7 @@ func containsText(box playwright.Locator, text string) bool {
 
 // SubmitPrompt clicks the send/submit button.
 func SubmitPrompt(page playwright.Page) (bool, error) {
-	btn := page.Locator("button[instrumentationid=\"prompt-submit-button\"]").Or(
-		page.GetByRole("button", playwright.PageGetByRoleOptions{
-			Name: regexp.MustCompile("(?i)submit|send"),
-		}),
-	).First()
+	btn := locate(page, "prompt.submit").First()
 
 	visible, _ := btn.IsVisible()
 	if !visible {
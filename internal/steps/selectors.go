@@ -0,0 +1,35 @@
+package steps
+
+import (
+	"sync/atomic"
+
+	playwright "github.com/playwright-community/playwright-go"
+
+	"vertex-nano-banana-unlimited/internal/selectors"
+)
+
+var registry atomic.Pointer[selectors.Registry]
+
+// UseSelectors 设置步骤使用的定位器注册表；未设置时使用内置定义。
+func UseSelectors(r *selectors.Registry) {
+	registry.Store(r)
+}
+
+func currentRegistry() *selectors.Registry {
+	if r := registry.Load(); r != nil {
+		return r
+	}
+	r, _ := selectors.New("")
+	registry.CompareAndSwap(nil, r)
+	return registry.Load()
+}
+
+// locate 在整个页面中按注册表中的 name 定位元素。
+func locate(page playwright.Page, name string) playwright.Locator {
+	return locateIn(page.Locator(":root"), name)
+}
+
+// locateIn 在 root 内按注册表中的 name 定位元素；每次调用都读取最新定义以支持热重载。
+func locateIn(root playwright.Locator, name string) playwright.Locator {
+	return currentRegistry().Get(name).Locate(root)
+}
@@ -3,7 +3,6 @@ package steps
 import (
 	"context"
 	"fmt"
-	"regexp"
 	"time"
 
 	playwright "github.com/playwright-community/playwright-go"
@@ -49,7 +48,7 @@ func AcceptTermsBlocking(page playwright.Page, timeout time.Duration) (bool, err
 			return true, nil
 		}
 
-		dialog := page.Locator(".mat-mdc-dialog-container").First()
+		dialog := locate(page, "terms.dialog").First()
 		if vis, _ := dialog.IsVisible(); !vis {
 			return true, nil // dialog not present; treat as already accepted
 		}
@@ -59,14 +58,12 @@ func AcceptTermsBlocking(page playwright.Page, timeout time.Duration) (bool, err
 }
 
 func acceptTerms(page playwright.Page) (bool, error) {
-	dialog := page.Locator(".mat-mdc-dialog-container").First()
+	dialog := locate(page, "terms.dialog").First()
 	if vis, _ := dialog.IsVisible(); !vis {
 		return false, nil
 	}
 
-	checkbox := dialog.GetByRole("checkbox", playwright.LocatorGetByRoleOptions{
-		Name: regexp.MustCompile("(?i)accept terms|accept|agree|接受|同意|使用条款"),
-	}).First()
+	checkbox := locateIn(dialog, "terms.checkbox").First()
 
 	if chkVisible, _ := checkbox.IsVisible(); !chkVisible {
 		return false, nil
@@ -75,9 +72,7 @@ func acceptTerms(page playwright.Page) (bool, error) {
 		_ = checkbox.Check()
 	}
 
-	submit := dialog.GetByRole("button", playwright.LocatorGetByRoleOptions{
-		Name: regexp.MustCompile("(?i)submit|accept|agree|continue|同意|提交"),
-	}).First()
+	submit := locateIn(dialog, "terms.submit").First()
 	if vis, _ := submit.IsVisible(); vis {
 		if err := submit.Click(); err != nil {
 			return false, err
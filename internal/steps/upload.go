@@ -2,7 +2,6 @@ package steps
 
 import (
 	"fmt"
-	"regexp"
 	"time"
 
 	playwright "github.com/playwright-community/playwright-go"
@@ -16,7 +15,7 @@ func UploadLocalFile(page playwright.Page, filePath string) (bool, error) {
 		return true, nil
 	}
 	fmt.Printf("🟦 Upload target: %s\n", filePath)
-	addBtn := page.Locator("ai-llm-prompt-input-actions-button button").First()
+	addBtn := locate(page, "upload.addButton").First()
 	err := addBtn.WaitFor(playwright.LocatorWaitForOptions{
 		State:   playwright.WaitForSelectorStateVisible,
 		Timeout: playwright.Float(5000),
@@ -29,10 +28,7 @@ func UploadLocalFile(page playwright.Page, filePath string) (bool, error) {
 	}
 	time.Sleep(300 * time.Millisecond)
 
-	menuRoot := page.Locator(".cdk-overlay-pane").Last()
-	uploadOption := menuRoot.Locator("a[role=\"menuitem\"]", playwright.LocatorLocatorOptions{
-		HasText: regexp.MustCompile("(?i)上传|提供本地文件|upload"),
-	}).First()
+	uploadOption := locate(page, "upload.menuItem").Last()
 
 	err = uploadOption.WaitFor(playwright.LocatorWaitForOptions{
 		State:   playwright.WaitForSelectorStateVisible,
@@ -55,3 +51,52 @@ func UploadLocalFile(page playwright.Page, filePath string) (bool, error) {
 	fmt.Println("🟦 File uploaded via chooser")
 	return true, nil
 }
+
+// AttachmentCount returns how many files are currently attached to the prompt.
+func AttachmentCount(page playwright.Page) int {
+	n, _ := locate(page, "upload.attachments").Count()
+	return n
+}
+
+// UploadLocalFiles attaches each file in order and waits until the prompt shows
+// one attachment per file. Files already attached by an earlier (finished) attempt are
+// skipped, so the step can be retried without duplicating attachments; the pipeline
+// waits for a timed-out attempt to end before retrying, so the count is settled here.
+// More attachments than files is reported as an error rather than silently retried.
+func UploadLocalFiles(page playwright.Page, filePaths []string) (bool, error) {
+	start := AttachmentCount(page)
+	if start > len(filePaths) {
+		return false, fmt.Errorf("unexpected extra attachments: want %d, got %d", len(filePaths), start)
+	}
+	if start == len(filePaths) {
+		return true, nil
+	}
+	for i := start; i < len(filePaths); i++ {
+		ok, err := UploadLocalFile(page, filePaths[i])
+		if err != nil || !ok {
+			return ok, err
+		}
+		if !waitAttachments(page, i+1, 15*time.Second) {
+			fmt.Printf("🟦 Attachment %d/%d not shown after upload\n", i+1, len(filePaths))
+			return false, nil
+		}
+	}
+	got := AttachmentCount(page)
+	if got != len(filePaths) {
+		return false, fmt.Errorf("attachment count mismatch: want %d, got %d", len(filePaths), got)
+	}
+	return true, nil
+}
+
+func waitAttachments(page playwright.Page, want int, within time.Duration) bool {
+	deadline := time.Now().Add(within)
+	for {
+		if AttachmentCount(page) >= want {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(300 * time.Millisecond)
+	}
+}
@@ -10,25 +10,63 @@ import (
 	"github.com/joho/godotenv"
 
 	"vertex-nano-banana-unlimited/internal/app"
+	"vertex-nano-banana-unlimited/internal/config"
 	"vertex-nano-banana-unlimited/internal/proxy"
 )
 
+const usage = `用法:
+  vertex-nano-banana-unlimited [serve]          启动 HTTP 服务（默认）
+  vertex-nano-banana-unlimited generate [flags] 不启动服务，直接生成并以 JSON 输出结果
+  vertex-nano-banana-unlimited batch [flags]    按 JSONL/CSV 清单批量生成，支持中断后恢复
+
+运行 "generate -h" 或 "batch -h" 查看参数。
+`
+
 func main() {
+	_ = godotenv.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if err := app.Configure(cfg); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	args := os.Args[1:]
+	cmd := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "serve":
+		serve(cfg.Server.Addr)
+	case "generate":
+		os.Exit(runGenerate(args))
+	case "batch":
+		os.Exit(runBatch(args))
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "未知子命令 %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+}
+
+func serve(addr string) {
 	preloadProxies(context.Background())
 	fmt.Println("🧪 HTTP 测试服务已启动：POST /run 支持 multipart（image/prompt/scenarioCount）或 JSON（image/prompt/scenarioCount）。")
+	fmt.Println("📋 /run 异步执行并返回 jobId：GET /jobs 列出最近任务，GET /jobs/{id} 查询状态与结果，GET /jobs/{id}/events 订阅步骤进度（SSE）")
+	fmt.Println("📦 批量生成：POST /batches 上传 JSONL/CSV 清单，GET /batches/{id} 查看进度与报告")
+	fmt.Println("🗂️ 历史记录：GET /history 支持 prompt/outcome/proxyTag/path/since/until 过滤与 limit/offset 分页")
 	fmt.Println("🩺 健康检查：GET /healthz")
 
-	// 固定端口配置
-	addr := ":8080"
-
-	fmt.Printf("🌐 服务器启动在端口 8080 (支持CORS跨域请求)\n")
+	fmt.Printf("🌐 服务器启动在 %s (支持CORS跨域请求)\n", addr)
 	if err := app.StartHTTPServer(context.Background(), addr); err != nil {
 		log.Fatalf("❌ HTTP 服务异常: %v", err)
 	}
 }
 
 func preloadProxies(ctx context.Context) {
-	_ = godotenv.Load()
 	sub := os.Getenv("PROXY_SINGBOX_SUB_URLS")
 	if strings.TrimSpace(sub) == "" {
 		fmt.Println("ℹ️ 启动时未配置 PROXY_SINGBOX_SUB_URLS（默认直连）")